@@ -0,0 +1,152 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is a file stored encrypted in the wallet with an entry
+type Attachment struct {
+	Name string
+	MIME string
+	Size int64
+	Data []byte
+}
+
+// NewAttachment create an attachment from a file
+func NewAttachment(path string, maxSize int64) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("%s is a directory", path)
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		return Attachment{}, fmt.Errorf("the file is too big: %s, the limit is %s", FormatSize(info.Size()), FormatSize(maxSize))
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return Attachment{
+		Name: filepath.Base(path),
+		MIME: mimeType,
+		Size: int64(len(data)),
+		Data: data,
+	}, nil
+}
+
+// Extract write the attachment in a file readable only by the user
+func (a *Attachment) Extract(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = file.Chmod(0600)
+	if err == nil {
+		_, err = file.Write(a.Data)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Attachment return the attachment with this name
+func (e *Entry) Attachment(name string) (Attachment, error) {
+	for _, attachment := range e.Attachments {
+		if attachment.Name == name {
+			return attachment, nil
+		}
+	}
+
+	return Attachment{}, fmt.Errorf("the attachment %s doesn't exist", name)
+}
+
+// AddAttachment add an attachment to an entry, an attachment with the same
+// name is replaced
+func (w *Wallet) AddAttachment(id string, attachment Attachment) error {
+	entry := w.SearchEntryByID(id)
+	if entry.ID == "" {
+		return fmt.Errorf("entry not found with this id")
+	}
+
+	var attachments []Attachment
+	for _, a := range entry.Attachments {
+		if a.Name != attachment.Name {
+			attachments = append(attachments, a)
+		}
+	}
+	entry.Attachments = append(attachments, attachment)
+
+	return w.UpdateEntry(entry)
+}
+
+// DeleteAttachment remove an attachment from an entry
+func (w *Wallet) DeleteAttachment(id string, name string) error {
+	entry := w.SearchEntryByID(id)
+	if entry.ID == "" {
+		return fmt.Errorf("entry not found with this id")
+	}
+
+	_, err := entry.Attachment(name)
+	if err != nil {
+		return err
+	}
+
+	var attachments []Attachment
+	for _, attachment := range entry.Attachments {
+		if attachment.Name != name {
+			attachments = append(attachments, attachment)
+		}
+	}
+	entry.Attachments = attachments
+
+	return w.UpdateEntry(entry)
+}
+
+// FormatSize return a size in bytes in a human readable format
+func FormatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value = value / 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+
+	return strings.Replace(fmt.Sprintf("%.1f %s", value, units[unit]), ".0 ", " ", 1)
+}
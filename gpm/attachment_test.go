@@ -0,0 +1,105 @@
+package gpm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func generateAttachmentFile(dir string, size int) string {
+	path := filepath.Join(dir, "id_rsa.txt")
+	ioutil.WriteFile(path, make([]byte, size), 0600)
+
+	return path
+}
+
+func TestNewAttachment(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+	path := generateAttachmentFile(dir, 2048)
+
+	_, err := NewAttachment(path, 1024)
+	if err == nil {
+		t.Error("a file bigger than the limit must return an error")
+	}
+
+	attachment, err := NewAttachment(path, 4096)
+	if err != nil {
+		t.Fatalf("a good file mustn't return an error: %s", err)
+	}
+	if attachment.Name != "id_rsa.txt" || attachment.Size != 2048 || len(attachment.Data) != 2048 {
+		t.Errorf("the attachment must have the file name and size: %s %d", attachment.Name, attachment.Size)
+	}
+	if attachment.MIME == "" {
+		t.Error("the attachment must have a MIME type")
+	}
+}
+
+func TestAddAndDeleteAttachment(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.MaxHistory = 10
+	attachment := Attachment{Name: "license.txt", MIME: "text/plain", Size: 3, Data: []byte("key")}
+
+	err := wallet.AddAttachment("BAD-ID", attachment)
+	if err == nil {
+		t.Error("add an attachment to an unknown entry must return an error")
+	}
+
+	err = wallet.AddAttachment("5", attachment)
+	if err != nil {
+		t.Errorf("add an attachment mustn't return an error: %s", err)
+	}
+	wallet.AddAttachment("5", attachment)
+
+	entry := wallet.SearchEntryByID("5")
+	if len(entry.Attachments) != 1 {
+		t.Errorf("an attachment with the same name must be replaced: %d", len(entry.Attachments))
+	}
+	if len(entry.History) != 0 {
+		t.Errorf("an attachment mustn't add a version in the history: %d", len(entry.History))
+	}
+
+	err = wallet.DeleteAttachment("5", "bad.txt")
+	if err == nil {
+		t.Error("delete an unknown attachment must return an error")
+	}
+
+	err = wallet.DeleteAttachment("5", "license.txt")
+	if err != nil {
+		t.Errorf("delete an attachment mustn't return an error: %s", err)
+	}
+	if len(wallet.SearchEntryByID("5").Attachments) != 0 {
+		t.Error("the attachment must be deleted")
+	}
+}
+
+func TestExtractAttachment(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "license.txt")
+	attachment := Attachment{Name: "license.txt", Data: []byte("key")}
+
+	err := attachment.Extract(path)
+	if err != nil {
+		t.Fatalf("extract an attachment mustn't return an error: %s", err)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("the extracted file must be readable only by the user: %s", info.Mode())
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != "key" {
+		t.Errorf("the extracted file must have the attachment content: %s", data)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for size, expected := range map[int64]string{10: "10 B", 2048: "2 KiB", 1536: "1.5 KiB", 3145728: "3 MiB"} {
+		if FormatSize(size) != expected {
+			t.Errorf("the size %d must be formatted as %s: %s", size, expected, FormatSize(size))
+		}
+	}
+}
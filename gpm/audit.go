@@ -0,0 +1,144 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	_ "embed"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed otp_sites.txt
+var otpSites string
+
+// Audit finding types
+const (
+	AuditReused   = "reused"
+	AuditWeak     = "weak"
+	AuditOld      = "old"
+	AuditInsecure = "insecure"
+	AuditNoOTP    = "no-otp"
+	AuditBreached = "breached"
+)
+
+// AuditOptions contains the thresholds of the audit
+type AuditOptions struct {
+	MinScore int
+	MaxAge   int
+	Breach   *BreachChecker
+}
+
+// AuditFinding is a security issue found on an entry
+type AuditFinding struct {
+	Type      string `json:"type" yaml:"type"`
+	EntryID   string `json:"entry_id" yaml:"entry_id"`
+	EntryName string `json:"entry_name" yaml:"entry_name"`
+	Message   string `json:"message" yaml:"message"`
+}
+
+// Audit check the passwords and the URIs of all the entries
+func (w *Wallet) Audit(options AuditOptions) []AuditFinding {
+	var findings []AuditFinding
+
+	passwords := make(map[string][]Entry)
+	for _, entry := range w.Entries {
+		if entry.Password != "" {
+			passwords[entry.Password] = append(passwords[entry.Password], entry)
+		}
+	}
+
+	for _, entry := range w.Entries {
+		finding := AuditFinding{EntryID: entry.ID, EntryName: entry.Name}
+
+		if others := passwords[entry.Password]; len(others) > 1 {
+			var names []string
+			for _, other := range others {
+				if other.ID != entry.ID {
+					names = append(names, other.Name)
+				}
+			}
+			finding.Type = AuditReused
+			finding.Message = fmt.Sprintf("the password is also used by %s", strings.Join(names, ", "))
+			findings = append(findings, finding)
+		}
+
+		if entry.Password != "" {
+			strength := PasswordStrength(entry.Password)
+			if strength.Score < options.MinScore {
+				finding.Type = AuditWeak
+				finding.Message = fmt.Sprintf("the password is %s (%.0f bits)", strength.Label(), strength.Entropy)
+				findings = append(findings, finding)
+			}
+		}
+
+		if entry.Password != "" && options.Breach != nil {
+			count, err := options.Breach.Count(entry.Password)
+			if err != nil {
+				finding.Type = AuditBreached
+				finding.Message = fmt.Sprintf("unable to check the data breaches: %s", err)
+				findings = append(findings, finding)
+			} else if count > 0 {
+				finding.Type = AuditBreached
+				finding.Message = fmt.Sprintf("the password appears %d times in the data breaches", count)
+				findings = append(findings, finding)
+			}
+		}
+
+		age := int(time.Since(time.Unix(entry.LastUpdate, 0)).Hours() / 24)
+		if options.MaxAge > 0 && entry.LastUpdate > 0 && age > options.MaxAge {
+			finding.Type = AuditOld
+			finding.Message = fmt.Sprintf("the password hasn't been changed for %d days", age)
+			findings = append(findings, finding)
+		}
+
+		uri, err := url.Parse(entry.URI)
+		if entry.URI == "" || err != nil {
+			continue
+		}
+
+		if uri.Scheme == "http" {
+			finding.Type = AuditInsecure
+			finding.Message = "the uri uses plain http"
+			findings = append(findings, finding)
+		}
+
+		if entry.OTP == "" && supportOTP(uri.Hostname()) {
+			finding.Type = AuditNoOTP
+			finding.Message = fmt.Sprintf("%s supports the two-factor authentication", uri.Hostname())
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return strings.ToLower(findings[i].EntryName) < strings.ToLower(findings[j].EntryName)
+	})
+
+	return findings
+}
+
+// supportOTP check if a host is known to support the OTP codes
+func supportOTP(host string) bool {
+	host = strings.ToLower(host)
+	for _, site := range strings.Fields(otpSites) {
+		if host == site || strings.HasSuffix(host, "."+site) {
+			return true
+		}
+	}
+
+	return false
+}
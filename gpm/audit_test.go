@@ -0,0 +1,105 @@
+package gpm
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func auditFindings(findings []AuditFinding, id string, findingType string) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.EntryID == id && finding.Type == findingType {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestAuditReusedPasswords(t *testing.T) {
+	var wallet Wallet
+
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1", Password: "x7#Kq9!vLm2$Rt5@"})
+	wallet.AddEntry(Entry{ID: "2", Name: "Entry 2", Password: "x7#Kq9!vLm2$Rt5@"})
+	wallet.AddEntry(Entry{ID: "3", Name: "Entry 3", Password: "Zp4&wQ8*nB3^yH6!"})
+
+	findings := wallet.Audit(AuditOptions{})
+	if auditFindings(findings, "1", AuditReused) != 1 || auditFindings(findings, "2", AuditReused) != 1 {
+		t.Errorf("the entries 1 and 2 must be reported as reused: %v", findings)
+	}
+	if auditFindings(findings, "3", AuditReused) != 0 {
+		t.Error("the entry 3 mustn't be reported as reused")
+	}
+}
+
+func TestAuditWeakPasswords(t *testing.T) {
+	var wallet Wallet
+
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1", Password: "password"})
+	wallet.AddEntry(Entry{ID: "2", Name: "Entry 2", Password: "x7#Kq9!vLm2$Rt5@"})
+
+	findings := wallet.Audit(AuditOptions{MinScore: 3})
+	if auditFindings(findings, "1", AuditWeak) != 1 {
+		t.Error("the entry 1 must be reported as weak")
+	}
+	if auditFindings(findings, "2", AuditWeak) != 0 {
+		t.Error("the entry 2 mustn't be reported as weak")
+	}
+}
+
+func TestAuditOldPasswords(t *testing.T) {
+	var wallet Wallet
+
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1"})
+	wallet.AddEntry(Entry{ID: "2", Name: "Entry 2"})
+	wallet.Entries[0].LastUpdate = time.Now().AddDate(-2, 0, 0).Unix()
+
+	findings := wallet.Audit(AuditOptions{MaxAge: 365})
+	if auditFindings(findings, "1", AuditOld) != 1 {
+		t.Error("the entry 1 must be reported as old")
+	}
+	if auditFindings(findings, "2", AuditOld) != 0 {
+		t.Error("the entry 2 mustn't be reported as old")
+	}
+}
+
+func TestAuditURIs(t *testing.T) {
+	var wallet Wallet
+
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1", URI: "http://intranet.example.com"})
+	wallet.AddEntry(Entry{ID: "2", Name: "Entry 2", URI: "https://github.com/login"})
+	wallet.AddEntry(Entry{ID: "3", Name: "Entry 3", URI: "https://gitlab.com", OTP: "JBSWY3DPEHPK3PXP"})
+
+	findings := wallet.Audit(AuditOptions{})
+	if auditFindings(findings, "1", AuditInsecure) != 1 {
+		t.Error("the entry 1 must be reported as insecure")
+	}
+	if auditFindings(findings, "1", AuditNoOTP) != 0 {
+		t.Error("the entry 1 mustn't be reported without OTP")
+	}
+	if auditFindings(findings, "2", AuditNoOTP) != 1 {
+		t.Error("the entry 2 must be reported without OTP")
+	}
+	if len(findings) != 2 {
+		t.Errorf("the audit must return 2 findings: %d", len(findings))
+	}
+}
+
+func TestAuditBreachedPasswords(t *testing.T) {
+	var wallet Wallet
+
+	path := generateBreachFile()
+	defer os.Remove(path)
+
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1", Password: "password42"})
+	wallet.AddEntry(Entry{ID: "2", Name: "Entry 2", Password: "x7#Kq9!vLm2$Rt5@"})
+
+	findings := wallet.Audit(AuditOptions{Breach: &BreachChecker{Path: path}})
+	if auditFindings(findings, "1", AuditBreached) != 1 {
+		t.Error("the entry 1 must be reported as breached")
+	}
+	if auditFindings(findings, "2", AuditBreached) != 0 {
+		t.Error("the entry 2 mustn't be reported as breached")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupTimeFormat is the time format in the backup file names
+const BackupTimeFormat = "20060102150405.000000000"
+
+// Backups return the backup files of the wallet from the newest to the oldest
+func (w *Wallet) Backups() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Dir(w.Path))
+	if err != nil {
+		return []string{}, err
+	}
+
+	var backups []string
+	prefix := filepath.Base(w.Path) + "."
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), prefix) || !strings.HasSuffix(file.Name(), ".bak") {
+			continue
+		}
+		path := filepath.Join(filepath.Dir(w.Path), file.Name())
+		if _, err := w.BackupTime(path); err == nil {
+			backups = append(backups, path)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	return backups, nil
+}
+
+// BackupTime return the date of a backup from its file name
+func (w *Wallet) BackupTime(path string) (time.Time, error) {
+	date := strings.TrimPrefix(filepath.Base(path), filepath.Base(w.Path)+".")
+	date = strings.TrimSuffix(date, ".bak")
+
+	return time.ParseInLocation(BackupTimeFormat, date, time.Local)
+}
+
+// RestoreBackup replace the wallet by a backup encrypted with the same passphrase
+func (w *Wallet) RestoreBackup(path string) error {
+	if w.ReadOnly {
+		return fmt.Errorf("the wallet is open in read-only mode")
+	}
+
+	backup := Wallet{Path: path, Passphrase: w.Passphrase, ReadOnly: true}
+	err := backup.Load()
+	if err != nil {
+		return fmt.Errorf("unable to decrypt the backup: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = w.Lock()
+	if err != nil {
+		return err
+	}
+
+	err = w.backup()
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(w.Path, content)
+	if err != nil {
+		return err
+	}
+
+	return w.Load()
+}
+
+// backup copy the current wallet file and remove the oldest backups
+func (w *Wallet) backup() error {
+	if w.MaxBackups <= 0 {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(w.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s.%s.bak", w.Path, time.Now().Format(BackupTimeFormat))
+	err = writeFileAtomic(path, content)
+	if err != nil {
+		return err
+	}
+
+	backups, err := w.Backups()
+	if err != nil {
+		return err
+	}
+
+	for i := w.MaxBackups; i < len(backups); i++ {
+		err = os.Remove(backups[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeBackups delete all the backups of the wallet
+func (w *Wallet) removeBackups() error {
+	backups, err := w.Backups()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups {
+		err = os.Remove(backup)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
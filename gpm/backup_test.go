@@ -0,0 +1,179 @@
+package gpm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupsRotation(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.MaxBackups = 2
+
+	for i := 0; i < 4; i++ {
+		err := wallet.Save()
+		if err != nil {
+			t.Errorf("save wallet mustn't return an error: %s", err)
+		}
+	}
+
+	backups, err := wallet.Backups()
+	if err != nil {
+		t.Errorf("list the backups mustn't return an error: %s", err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("must have 2 backups: %d", len(backups))
+	}
+
+	first, _ := wallet.BackupTime(backups[0])
+	second, err := wallet.BackupTime(backups[1])
+	if err != nil {
+		t.Errorf("the backup time mustn't return an error: %s", err)
+	}
+	if !first.After(second) {
+		t.Errorf("the backups must be sorted from the newest: %s", backups)
+	}
+}
+
+func TestBackupsSkipUnknownFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.MaxBackups = 2
+	wallet.Save()
+	wallet.Save()
+	ioutil.WriteFile(filepath.Join(dir, "test.gpm.old.bak"), []byte("old"), 0600)
+
+	backups, err := wallet.Backups()
+	if err != nil {
+		t.Errorf("list the backups mustn't return an error: %s", err)
+	}
+	if len(backups) != 1 {
+		t.Errorf("a file without date mustn't be a backup: %s", backups)
+	}
+}
+
+func TestBackupsDisabled(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.Save()
+	wallet.Save()
+
+	backups, _ := wallet.Backups()
+	if len(backups) != 0 {
+		t.Errorf("must have 0 backup: %d", len(backups))
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.MaxBackups = 5
+	wallet.Save()
+
+	wallet.DeleteEntry("5")
+	wallet.Save()
+
+	backups, _ := wallet.Backups()
+	err := wallet.RestoreBackup(backups[0])
+	if err != nil {
+		t.Errorf("restore a backup mustn't return an error: %s", err)
+	}
+
+	entries := len(wallet.Entries)
+	if entries != 10 {
+		t.Errorf("must have 10 entries after the restore: %d", entries)
+	}
+
+	backups, _ = wallet.Backups()
+	if len(backups) != 2 {
+		t.Errorf("the restore must backup the current wallet: %d", len(backups))
+	}
+
+	wallet.Passphrase = "bad secret"
+	err = wallet.RestoreBackup(backups[0])
+	if err == nil {
+		t.Error("restore a backup with a bad passphrase must return an error")
+	}
+}
+
+func TestRestoreBackupReadOnly(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.MaxBackups = 5
+	wallet.Save()
+	wallet.DeleteEntry("5")
+	wallet.Save()
+	defer wallet.Unlock()
+
+	readOnly := Wallet{Path: wallet.Path, Passphrase: "secret", ReadOnly: true, MaxBackups: 5}
+	err := readOnly.Load()
+	if err != nil {
+		t.Fatalf("load a wallet in read-only mustn't return an error: %s", err)
+	}
+
+	backups, _ := readOnly.Backups()
+	err = readOnly.RestoreBackup(backups[0])
+	if err == nil {
+		t.Error("restore a backup in read-only must return an error")
+	}
+
+	backups, _ = readOnly.Backups()
+	if len(backups) != 1 {
+		t.Errorf("restore a backup in read-only mustn't change the backups: %d", len(backups))
+	}
+}
+
+func TestChangePassphraseRemoveBackups(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.MaxBackups = 5
+	wallet.Save()
+	wallet.DeleteEntry("5")
+	wallet.Save()
+
+	err := wallet.ChangePassphrase("secret", "new secret")
+	if err != nil {
+		t.Fatalf("change passphrase mustn't return an error: %s", err)
+	}
+	wallet.Unlock()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.bak"))
+	for _, file := range files {
+		backup := Wallet{Path: file, Passphrase: "secret", ReadOnly: true}
+		if backup.Load() == nil {
+			t.Errorf("a backup mustn't be encrypted with the old passphrase: %s", file)
+		}
+	}
+
+	wallet.Save()
+	backups, _ := wallet.Backups()
+	if len(backups) != 1 {
+		t.Errorf("the saves after the change must be backed up: %d", len(backups))
+	}
+}
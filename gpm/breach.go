@@ -0,0 +1,169 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachChecker search the passwords in a local copy of the Have I Been Pwned
+// SHA-1 hashes, the path is a directory with the range files (named with the
+// first 5 chars of the hash and containing the suffixes) or a file with all
+// the hashes ordered by hash
+type BreachChecker struct {
+	Path string
+}
+
+// Count return how many times the password appears in the breaches
+func (b *BreachChecker) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	if info.IsDir() {
+		return b.countInRange(hash)
+	}
+
+	return b.countInFile(hash)
+}
+
+// countInRange search the hash suffix in the range file of the hash prefix
+func (b *BreachChecker) countInRange(hash string) (int, error) {
+	var file *os.File
+	var err error
+
+	for _, name := range []string{hash[:5], hash[:5] + ".txt", strings.ToLower(hash[:5])} {
+		file, err = os.Open(filepath.Join(b.Path, name))
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	if file == nil {
+		return 0, nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		suffix, count := parseBreachLine(scanner.Text())
+		if suffix == hash[5:] {
+			return count, nil
+		}
+	}
+
+	return 0, scanner.Err()
+}
+
+// countInFile search the hash in the ordered file with a binary search
+func (b *BreachChecker) countInFile(hash string) (int, error) {
+	file, err := os.Open(b.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	low, high := int64(0), info.Size()
+	for low < high {
+		middle := (low + high) / 2
+		line, next, err := readLineFrom(file, middle)
+		if err != nil {
+			return 0, err
+		}
+		if line == "" {
+			high = middle
+			continue
+		}
+
+		lineHash, count := parseBreachLine(line)
+		switch {
+		case hash == lineHash:
+			return count, nil
+		case hash < lineHash:
+			high = middle
+		default:
+			low = next
+		}
+	}
+
+	return 0, nil
+}
+
+// readLineFrom return the first line starting at or after the offset and the
+// offset of the next line
+func readLineFrom(file *os.File, offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+
+	_, err := file.Seek(start, io.SeekStart)
+	if err != nil {
+		return "", 0, err
+	}
+
+	reader := bufio.NewReader(file)
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return "", 0, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		start = start + int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+
+	return strings.TrimSpace(line), start + int64(len(line)), nil
+}
+
+// parseBreachLine return the hash and the count of a line HASH:COUNT
+func parseBreachLine(line string) (string, int) {
+	fields := strings.SplitN(strings.TrimSpace(line), ":", 2)
+	hash := strings.ToUpper(fields[0])
+	if len(fields) != 2 {
+		return hash, 1
+	}
+
+	count, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return hash, 1
+	}
+
+	return hash, count
+}
@@ -0,0 +1,85 @@
+package gpm
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func breachHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func generateBreachFile() string {
+	var lines []string
+
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("%s:%d", breachHash(fmt.Sprintf("password%d", i)), i+1))
+	}
+	sort.Strings(lines)
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	tmpFile.WriteString(strings.Join(lines, "\r\n") + "\r\n")
+	tmpFile.Close()
+
+	return tmpFile.Name()
+}
+
+func TestBreachCountInFile(t *testing.T) {
+	path := generateBreachFile()
+	defer os.Remove(path)
+
+	breach := BreachChecker{Path: path}
+	for _, i := range []int{0, 1, 250, 498, 499} {
+		count, err := breach.Count(fmt.Sprintf("password%d", i))
+		if err != nil {
+			t.Errorf("count mustn't return an error: %s", err)
+		}
+		if count != i+1 {
+			t.Errorf("the password%d must appear %d times: %d", i, i+1, count)
+		}
+	}
+
+	count, err := breach.Count("x7#Kq9!vLm2$Rt5@")
+	if err != nil || count != 0 {
+		t.Errorf("a password not in the file must appear 0 times: %d", count)
+	}
+}
+
+func TestBreachCountInRange(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	hash := breachHash("password")
+	content := fmt.Sprintf("0018A45C4D1DEF81644B54AB7F969B88D65:1\n%s:42\n", hash[5:])
+	ioutil.WriteFile(filepath.Join(dir, hash[:5]), []byte(content), 0600)
+
+	breach := BreachChecker{Path: dir}
+	count, err := breach.Count("password")
+	if err != nil {
+		t.Errorf("count mustn't return an error: %s", err)
+	}
+	if count != 42 {
+		t.Errorf("the password must appear 42 times: %d", count)
+	}
+
+	count, err = breach.Count("x7#Kq9!vLm2$Rt5@")
+	if err != nil || count != 0 {
+		t.Errorf("a password without range file must appear 0 times: %d", count)
+	}
+}
+
+func TestBreachCountWithBadPath(t *testing.T) {
+	breach := BreachChecker{Path: "/bad/path"}
+	_, err := breach.Count("password")
+	if err == nil {
+		t.Error("count with a bad path must return an error")
+	}
+}
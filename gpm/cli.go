@@ -1,10 +1,15 @@
 package gpm
 
 import (
+	"errors"
 	"fmt"
 	"flag"
+	"io"
 	"io/ioutil"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	ui "github.com/gizak/termui/v3"
@@ -18,18 +23,54 @@ var (
 	CONFIG  = flag.String("config", "", "specify the config file")
 	WALLET  = flag.String("wallet", "", "specify the wallet")
 	PASSWD  = flag.Bool("password", false, "generate and print a random password")
-	DIGIT   = flag.Bool("digit", false, "use digit to generate a random password")
+	PROFILE = flag.String("profile", "", "use a password profile from the config to generate a random password")
+	PHRASE  = flag.Bool("passphrase", false, "generate and print a random passphrase")
+	WORDS   = flag.Int("words", 6, "specify the number of words in the passphrase")
+	SEP     = flag.String("separator", "-", "specify the separator between the words of the passphrase")
+	CAPITAL = flag.Bool("capitalize", false, "capitalize the words of the passphrase")
+	DIGIT   = flag.Bool("digit", false, "use digit to generate a random password or passphrase")
 	LETTER  = flag.Bool("letter", false, "use letter to generate a random password")
 	SPECIAL = flag.Bool("special", false, "use special chars to generate a random password")
 	EXPORT  = flag.String("export", "", "json file path to export a wallet")
 	IMPORT  = flag.String("import", "", "json file path to import entries")
+	AUDIT   = flag.Bool("audit", false, "print a security report of the wallet")
+	BREACH  = flag.String("breaches", "", "specify the Have I Been Pwned hashes file or directory")
+	OUTPUT  = flag.String("output", "table", "specify the output format: table, json or yaml")
+	SOURCE  = flag.String("passphrase-from", "", "read the passphrase from: prompt, stdin, fd:N, env:NAME or command (passphrase_command in the config)")
+	SECRETS = flag.Bool("show-secrets", false, "print the passwords and the secrets in all the output formats")
+	RESTORE = flag.Bool("restore", false, "list the backups and restore one")
+	REKEY   = flag.Bool("change-passphrase", false, "change the wallet passphrase")
+	MERGE   = flag.Bool("merge", false, "merge the two wallet files given as arguments")
+	BASE    = flag.String("merge-base", "", "specify the wallet file of the common ancestor to merge")
+	MERGED  = flag.String("merge-output", "", "specify the merged wallet file, the first wallet file by default")
+	SYNC    = flag.Bool("sync", false, "pull and push the wallet with the git remote")
+	ENTRY   = flag.String("entry", "", "specify the entry name or ID for the attachments")
+	ATTACH  = flag.String("attach", "", "file path to attach to the entry")
+	FILES   = flag.Bool("attachments", false, "list the attachments of the entry")
+	EXTRACT = flag.String("extract", "", "name of the attachment to extract in the file given as argument")
+	DETACH  = flag.String("detach", "", "name of the attachment to remove from the entry")
+	RENAME  = flag.String("rename-group", "", "rename or move a group with its sub groups to the group given as argument")
+	MERGEG  = flag.String("merge-groups", "", "comma separated groups to merge in the group given as argument")
+	DELETEG = flag.String("delete-group", "", "delete a group with its sub groups, the entries are ungrouped")
+	TRASH   = flag.Bool("trash", false, "move the entries of the deleted group to the trash")
 	HELP    = flag.Bool("help", false, "print this help message")
 )
 
 // Cli struct
 type Cli struct {
-	Config Config
-	Wallet Wallet
+	Config     Config
+	Wallet     Wallet
+	Passphrase string
+}
+
+// ReadOnlyBox print a notification and return true if the wallet is open
+// in read-only mode, before an action changing the wallet
+func (c *Cli) ReadOnlyBox() bool {
+	if c.Wallet.ReadOnly {
+		c.NotificationBox("the wallet is open in read-only mode", true)
+	}
+
+	return c.Wallet.ReadOnly
 }
 
 // NotificationBox print a notification
@@ -76,6 +117,16 @@ func (c *Cli) ChoiceBox(title string, choice bool) bool {
 
 // InputBox is string form
 func (c *Cli) InputBox(title string, input string, hidden bool) string {
+	return c.inputBox(title, input, hidden, false)
+}
+
+// PasswordInputBox is a hidden string form with a strength meter
+func (c *Cli) PasswordInputBox(title string, input string) string {
+	return c.inputBox(title, input, true, true)
+}
+
+// inputBox is string form, with a strength meter if needed
+func (c *Cli) inputBox(title string, input string, hidden bool, meter bool) string {
 	var secret string
 
 	p := widgets.NewParagraph()
@@ -83,9 +134,21 @@ func (c *Cli) InputBox(title string, input string, hidden bool) string {
 	p.Title = title
 	p.Text = input
 
+	g := widgets.NewGauge()
+	g.SetRect(10, 10, 70, 13)
+	g.Title = "Strength"
+
 	uiEvents := ui.PollEvents()
 	for {
-		ui.Render(p)
+		if meter {
+			strength := PasswordStrength(input)
+			g.Percent = int(math.Min(strength.Entropy, 100))
+			g.Label = fmt.Sprintf("%s (%.0f bits)", strength.Label(), strength.Entropy)
+			g.BarColor = ui.StyleParserColorMap[strength.Color()]
+			ui.Render(p, g)
+		} else {
+			ui.Render(p)
+		}
 		e := <-uiEvents
 		switch e.ID {
 		case "q", "<C-c>":
@@ -154,27 +217,266 @@ func (c *Cli) EntryBox(entry Entry) {
 	p.SetRect(25, 0, 80, 20)
 	p.Text = fmt.Sprintf("%s[Name:](fg:yellow) %s\n", p.Text, entry.Name)
 	p.Text = fmt.Sprintf("%s[Group:](fg:yellow) %s\n", p.Text, entry.Group)
+	if len(entry.Tags) > 0 {
+		p.Text = fmt.Sprintf("%s[Tags:](fg:yellow) %s\n", p.Text, strings.Join(entry.Tags, ", "))
+	}
 	p.Text = fmt.Sprintf("%s[URI:](fg:yellow) %s\n", p.Text, entry.URI)
 	p.Text = fmt.Sprintf("%s[User:](fg:yellow) %s\n", p.Text, entry.User)
+	if entry.Password != "" {
+		strength := PasswordStrength(entry.Password)
+		p.Text = fmt.Sprintf("%s[Strength:](fg:yellow) [%s](fg:%s)\n", p.Text, strength.Label(), strength.Color())
+	}
+	if entry.Password != "" && c.Config.BreachPath != "" {
+		breach := BreachChecker{Path: c.Config.BreachPath}
+		count, err := breach.Count(entry.Password)
+		if err != nil {
+			p.Text = fmt.Sprintf("%s[Breached:](fg:yellow) [unknown](fg:red)\n", p.Text)
+		} else if count > 0 {
+			p.Text = fmt.Sprintf("%s[Breached:](fg:yellow) [yes, %d times](fg:red)\n", p.Text, count)
+		} else {
+			p.Text = fmt.Sprintf("%s[Breached:](fg:yellow) [no](fg:green)\n", p.Text)
+		}
+	}
+	if expire := entry.ExpireDate(); expire > 0 {
+		color := "green"
+		if entry.Expired(time.Now().AddDate(0, 0, c.Config.ExpireWarning)) {
+			color = "red"
+		}
+		date := time.Unix(expire, 0).Format(ExpireDateFormat)
+		p.Text = fmt.Sprintf("%s[Expire:](fg:yellow) [%s](fg:%s)\n", p.Text, date, color)
+	}
 	if entry.OTP == "" {
 		p.Text = fmt.Sprintf("%s[OTP:](fg:yellow) [no](fg:red)\n", p.Text)
 	} else {
 		p.Text = fmt.Sprintf("%s[OTP:](fg:yellow) [yes](fg:green)\n", p.Text)
 	}
 	p.Text = fmt.Sprintf("%s[Comment:](fg:yellow) %v\n", p.Text, entry.Comment)
+	for _, field := range entry.Fields {
+		p.Text = fmt.Sprintf("%s[%s:](fg:yellow) %s\n", p.Text, field.Name, field.Display())
+	}
+	for _, attachment := range entry.Attachments {
+		p.Text = fmt.Sprintf("%s[Attachment:](fg:yellow) %s (%s)\n", p.Text, attachment.Name, FormatSize(attachment.Size))
+	}
+	if len(entry.History) > 0 {
+		p.Text = fmt.Sprintf("%s[History:](fg:yellow) %d versions\n", p.Text, len(entry.History))
+	}
 
 	ui.Render(p)
 }
 
-// GroupsBox to select a group
+// GroupsBox to select a group in the groups tree
 func (c *Cli) GroupsBox() string {
+	groups := c.Wallet.GroupTree()
+	expanded := make(map[string]bool)
+
 	l := widgets.NewList()
 	l.Title = "Groups"
 	l.TextStyle = ui.NewStyle(ui.ColorYellow)
 	l.SelectedRowStyle = ui.NewStyle(ui.ColorGreen, ui.ColorClear, ui.ModifierBold)
 	l.WrapText = false
 	l.SetRect(25, 0, 80, 20)
-	l.Rows = append(c.Wallet.Groups(), "No group")
+
+	uiEvents := ui.PollEvents()
+	for {
+		var visible []string
+		l.Rows = []string{}
+		for i, group := range groups {
+			if !groupVisible(group, expanded) {
+				continue
+			}
+
+			marker := "  "
+			if i+1 < len(groups) && InGroup(groups[i+1], group) {
+				marker = "+ "
+				if expanded[strings.ToLower(group)] {
+					marker = "- "
+				}
+			}
+			names := strings.Split(group, GroupSeparator)
+			l.Rows = append(l.Rows, strings.Repeat("  ", GroupDepth(group))+marker+names[len(names)-1])
+			visible = append(visible, group)
+		}
+		l.Rows = append(l.Rows, "No group")
+		if l.SelectedRow >= len(l.Rows) {
+			l.SelectedRow = len(l.Rows) - 1
+		}
+
+		ui.Render(l)
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<C-c>", "<Escape>":
+			return ""
+		case "<Enter>":
+			if l.SelectedRow == len(visible) {
+				return "No group"
+			}
+			return visible[l.SelectedRow]
+		case "l", "<Right>":
+			if l.SelectedRow < len(visible) {
+				expanded[strings.ToLower(visible[l.SelectedRow])] = true
+			}
+		case "<Space>":
+			if l.SelectedRow < len(visible) {
+				group := strings.ToLower(visible[l.SelectedRow])
+				expanded[group] = !expanded[group]
+			}
+		case "h", "<Left>":
+			if l.SelectedRow < len(visible) {
+				expanded[strings.ToLower(visible[l.SelectedRow])] = false
+			}
+		case "r", "m", "d":
+			if l.SelectedRow >= len(visible) || c.ReadOnlyBox() {
+				continue
+			}
+			updated := false
+			switch e.ID {
+			case "r":
+				updated = c.RenameGroup(visible[l.SelectedRow])
+			case "m":
+				updated = c.MergeGroup(visible[l.SelectedRow])
+			case "d":
+				updated = c.DeleteGroup(visible[l.SelectedRow])
+			}
+			if updated {
+				groups = c.Wallet.GroupTree()
+			}
+			ui.Clear()
+		case "j", "<Down>":
+			l.ScrollDown()
+		case "k", "<Up>":
+			l.ScrollUp()
+		}
+	}
+}
+
+// groupVisible return true if all the parents of the group are expanded
+func groupVisible(group string, expanded map[string]bool) bool {
+	names := strings.Split(strings.ToLower(group), GroupSeparator)
+	for i := 1; i < len(names); i++ {
+		if !expanded[strings.Join(names[:i], GroupSeparator)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MergeGroup to move all the entries of a group in another group
+func (c *Cli) MergeGroup(group string) bool {
+	var groups []string
+	for _, g := range c.Wallet.GroupTree() {
+		if !InGroup(g, group) {
+			groups = append(groups, g)
+		}
+	}
+
+	target := c.SelectBox(fmt.Sprintf("Merge the group %s in", group), groups)
+	if target == "" || !c.ChoiceBox(fmt.Sprintf("Do you want merge %s in %s ?", group, target), false) {
+		return false
+	}
+
+	count, err := c.Wallet.MergeGroups([]string{group}, target)
+	if err == nil {
+		err = c.Wallet.Save()
+	}
+	if err != nil {
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+		return false
+	}
+
+	c.NotificationBox(fmt.Sprintf("%d entries have been moved", count), false)
+	return true
+}
+
+// DeleteGroup to delete a group, the entries are ungrouped or moved to the trash
+func (c *Cli) DeleteGroup(group string) bool {
+	choice := c.SelectBox(fmt.Sprintf("Delete the group %s", group),
+		[]string{"Ungroup the entries", "Move the entries to the trash"})
+	if choice == "" || !c.ChoiceBox(fmt.Sprintf("Do you want delete the group %s ?", group), false) {
+		return false
+	}
+
+	count, err := c.Wallet.DeleteGroup(group, choice == "Move the entries to the trash")
+	if err == nil {
+		err = c.Wallet.Save()
+	}
+	if err != nil {
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+		return false
+	}
+
+	c.NotificationBox(fmt.Sprintf("%d entries have been updated", count), false)
+	return true
+}
+
+// RenameGroup to rename or move a group with its descendants
+func (c *Cli) RenameGroup(group string) bool {
+	newGroup := c.InputBox(fmt.Sprintf("Rename or move the group %s", group), group, false)
+	if newGroup == "" || newGroup == group {
+		return false
+	}
+
+	count, err := c.Wallet.RenameGroup(group, newGroup)
+	if err == nil {
+		err = c.Wallet.Save()
+	}
+	if err != nil {
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+		return false
+	}
+
+	c.NotificationBox(fmt.Sprintf("%d entries have been moved", count), false)
+	return true
+}
+
+// TagsBox to select a tag to filter the entries
+func (c *Cli) TagsBox() string {
+	tags := c.Wallet.Tags()
+
+	l := widgets.NewList()
+	l.Title = "Tags"
+	l.TextStyle = ui.NewStyle(ui.ColorYellow)
+	l.SelectedRowStyle = ui.NewStyle(ui.ColorGreen, ui.ColorClear, ui.ModifierBold)
+	l.WrapText = false
+	l.SetRect(25, 0, 80, 20)
+	l.Rows = []string{"All"}
+	for _, tag := range tags {
+		l.Rows = append(l.Rows, fmt.Sprintf("%s (%d)", tag.Name, tag.Count))
+	}
+
+	uiEvents := ui.PollEvents()
+	for {
+		ui.Render(l)
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<C-c>", "<Escape>":
+			return ""
+		case "<Enter>":
+			if l.SelectedRow == 0 {
+				return ""
+			}
+			return tags[l.SelectedRow-1].Name
+		case "j", "<Down>":
+			l.ScrollDown()
+		case "k", "<Up>":
+			l.ScrollUp()
+		}
+	}
+}
+
+// AuditBox to select an entry from the audit findings
+func (c *Cli) AuditBox() string {
+	findings := c.Wallet.Audit(c.Config.AuditOptions())
+
+	l := widgets.NewList()
+	l.Title = fmt.Sprintf("Audit: %d findings", len(findings))
+	l.TextStyle = ui.NewStyle(ui.ColorYellow)
+	l.SelectedRowStyle = ui.NewStyle(ui.ColorGreen, ui.ColorClear, ui.ModifierBold)
+	l.WrapText = false
+	l.SetRect(25, 0, 80, 20)
+	for _, finding := range findings {
+		l.Rows = append(l.Rows, fmt.Sprintf("%s: %s", finding.EntryName, finding.Message))
+	}
 
 	uiEvents := ui.PollEvents()
 	for {
@@ -187,7 +489,7 @@ func (c *Cli) GroupsBox() string {
 			if len(l.Rows) == 0 {
 				return ""
 			}
-			return l.Rows[l.SelectedRow]
+			return findings[l.SelectedRow].EntryID
 		case "j", "<Down>":
 			if len(l.Rows) > 0 {
 				l.ScrollDown()
@@ -200,10 +502,338 @@ func (c *Cli) GroupsBox() string {
 	}
 }
 
+// ConflictBox to choose the version to keep for an entry modified by another program
+func (c *Cli) ConflictBox(conflict MergeConflict) *Entry {
+	ui.Clear()
+	for i, entry := range []*Entry{conflict.Ours, conflict.Theirs} {
+		p := widgets.NewParagraph()
+		p.SetRect(i*40, 10, i*40+40, 22)
+		p.Title = []string{"My version", "Other version"}[i]
+		if entry == nil {
+			p.Text = "[deleted](fg:red)"
+		} else {
+			p.Text = fmt.Sprintf("%s[Name:](fg:yellow) %s\n", p.Text, entry.Name)
+			p.Text = fmt.Sprintf("%s[Group:](fg:yellow) %s\n", p.Text, entry.Group)
+			p.Text = fmt.Sprintf("%s[URI:](fg:yellow) %s\n", p.Text, entry.URI)
+			p.Text = fmt.Sprintf("%s[User:](fg:yellow) %s\n", p.Text, entry.User)
+			p.Text = fmt.Sprintf("%s[Update:](fg:yellow) %s\n", p.Text,
+				time.Unix(entry.LastUpdate, 0).Format("2006-01-02 15:04:05"))
+		}
+		ui.Render(p)
+	}
+
+	title := fmt.Sprintf("Conflict on %s", conflict.Name())
+	choice := c.SelectBox(title, []string{"Keep my version", "Keep the other version"})
+	ui.Clear()
+	switch choice {
+	case "Keep my version":
+		return conflict.Ours
+	case "Keep the other version":
+		return conflict.Theirs
+	}
+
+	return ResolveNewest(conflict)
+}
+
+// HistoryBox to copy the password or restore an old version of an entry
+func (c *Cli) HistoryBox(entry Entry) bool {
+	if len(entry.History) == 0 {
+		c.NotificationBox("the entry hasn't history", false)
+		return false
+	}
+
+	l := widgets.NewList()
+	l.Title = fmt.Sprintf("History: %s", entry.Name)
+	l.TextStyle = ui.NewStyle(ui.ColorYellow)
+	l.SelectedRowStyle = ui.NewStyle(ui.ColorGreen, ui.ColorClear, ui.ModifierBold)
+	l.WrapText = false
+	l.SetRect(25, 0, 80, 20)
+	for _, version := range entry.History {
+		date := time.Unix(version.LastUpdate, 0).Format("2006-01-02 15:04:05")
+		l.Rows = append(l.Rows, fmt.Sprintf("%s %s (%s)", date, version.Name, version.User))
+	}
+
+	uiEvents := ui.PollEvents()
+	for {
+		ui.Render(l)
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<C-c>", "<Escape>":
+			return false
+		case "<Enter>":
+			c.EntryBox(entry.History[l.SelectedRow])
+			choice := c.SelectBox("Action", []string{"Copy the password", "Restore this version"})
+			switch choice {
+			case "Copy the password":
+				clipboard.WriteAll(entry.History[l.SelectedRow].Password)
+				c.NotificationBox("the password is copied in clipboard", false)
+				return false
+			case "Restore this version":
+				if c.ReadOnlyBox() {
+					return false
+				}
+				err := c.Wallet.RestoreEntry(entry.ID, l.SelectedRow)
+				if err == nil {
+					err = c.Wallet.Save()
+				}
+				if err != nil {
+					c.NotificationBox(fmt.Sprintf("%s", err), true)
+					return false
+				}
+				return true
+			}
+			ui.Clear()
+		case "j", "<Down>":
+			l.ScrollDown()
+		case "k", "<Up>":
+			l.ScrollUp()
+		}
+	}
+}
+
+// TrashBox to restore or purge the deleted entries
+func (c *Cli) TrashBox() bool {
+	if len(c.Wallet.Trash) == 0 {
+		c.NotificationBox("the trash is empty", false)
+		return false
+	}
+
+	l := widgets.NewList()
+	l.Title = fmt.Sprintf("Trash: %d entries", len(c.Wallet.Trash))
+	l.TextStyle = ui.NewStyle(ui.ColorYellow)
+	l.SelectedRowStyle = ui.NewStyle(ui.ColorGreen, ui.ColorClear, ui.ModifierBold)
+	l.WrapText = false
+	l.SetRect(25, 0, 80, 20)
+	for _, entry := range c.Wallet.Trash {
+		date := time.Unix(entry.Deleted, 0).Format("2006-01-02 15:04:05")
+		l.Rows = append(l.Rows, fmt.Sprintf("%s %s", date, entry.Name))
+	}
+
+	uiEvents := ui.PollEvents()
+	for {
+		ui.Render(l)
+		e := <-uiEvents
+		switch e.ID {
+		case "q", "<C-c>", "<Escape>":
+			return false
+		case "<Enter>":
+			var err error
+			entry := c.Wallet.Trash[l.SelectedRow]
+			c.EntryBox(entry)
+			choice := c.SelectBox("Action", []string{"Restore the entry", "Delete definitively the entry", "Empty the trash"})
+			if choice != "" && c.ReadOnlyBox() {
+				return false
+			}
+			switch choice {
+			case "Restore the entry":
+				err = c.Wallet.RestoreTrashEntry(entry.ID)
+			case "Delete definitively the entry":
+				if !c.ChoiceBox("Do you want delete definitively this entry ?", false) {
+					return false
+				}
+				err = c.Wallet.PurgeEntry(entry.ID)
+			case "Empty the trash":
+				if !c.ChoiceBox("Do you want delete definitively all the entries ?", false) {
+					return false
+				}
+				c.Wallet.EmptyTrash()
+			default:
+				ui.Clear()
+				continue
+			}
+			if err == nil {
+				err = c.Wallet.Save()
+			}
+			if err != nil {
+				c.NotificationBox(fmt.Sprintf("%s", err), true)
+				return false
+			}
+			return true
+		case "j", "<Down>":
+			l.ScrollDown()
+		case "k", "<Up>":
+			l.ScrollUp()
+		}
+	}
+}
+
+// FieldsBox to add, update or delete the custom fields
+func (c *Cli) FieldsBox(fields []CustomField) []CustomField {
+	fields = append([]CustomField{}, fields...)
+
+	for {
+		var items []string
+		for _, field := range fields {
+			items = append(items, fmt.Sprintf("%s (%s): %s", field.Name, field.Type, field.Display()))
+		}
+		items = append(items, "* Add a new field *", "* Done *")
+
+		item := c.SelectBox("Custom fields", items)
+		switch item {
+		case "", "* Done *":
+			return fields
+		case "* Add a new field *":
+			field, ok := c.FieldBox(CustomField{Type: FieldText})
+			if ok {
+				fields = append(fields, field)
+			}
+		default:
+			for i := range fields {
+				if items[i] != item {
+					continue
+				}
+				switch c.SelectBox(fields[i].Name, []string{"Update the field", "Delete the field"}) {
+				case "Update the field":
+					field, ok := c.FieldBox(fields[i])
+					if ok {
+						fields[i] = field
+					}
+				case "Delete the field":
+					fields = append(fields[:i], fields[i+1:]...)
+				}
+				break
+			}
+		}
+	}
+}
+
+// FieldBox to enter the name, the type and the value of a custom field
+func (c *Cli) FieldBox(field CustomField) (CustomField, bool) {
+	field.Name = c.InputBox("Field name", field.Name, false)
+	if fieldType := c.SelectBox("Field type", FieldTypes); fieldType != "" {
+		field.Type = fieldType
+	}
+	field.Value = c.InputBox("Field value", field.Value, field.Secret())
+
+	err := field.Verify()
+	if err != nil {
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+		return field, false
+	}
+
+	return field, true
+}
+
+// CopyFieldBox to copy a custom field value in clipboard
+func (c *Cli) CopyFieldBox(entry Entry) {
+	var names []string
+
+	if len(entry.Fields) == 0 {
+		c.NotificationBox("the entry hasn't custom fields", false)
+		return
+	}
+
+	for _, field := range entry.Fields {
+		names = append(names, field.Name)
+	}
+
+	name := c.SelectBox("Field to copy", names)
+	field, err := entry.Field(name)
+	if err != nil {
+		return
+	}
+
+	clipboard.WriteAll(field.Value)
+	c.NotificationBox(fmt.Sprintf("the field %s is copied in clipboard", field.Name), false)
+}
+
+// AttachmentsBox to attach, extract or remove the files of an entry
+func (c *Cli) AttachmentsBox(entry Entry) bool {
+	var items []string
+	var err error
+
+	for _, attachment := range entry.Attachments {
+		items = append(items, attachment.Name)
+	}
+	item := c.SelectBox("Attachments", append(items, "* Attach a file *"))
+
+	switch item {
+	case "":
+		return false
+	case "* Attach a file *":
+		if c.ReadOnlyBox() {
+			return false
+		}
+		var attachment Attachment
+		attachment, err = NewAttachment(c.InputBox("File path", "", false), c.Config.AttachmentMaxSize)
+		if err == nil {
+			err = c.Wallet.AddAttachment(entry.ID, attachment)
+		}
+	default:
+		switch c.SelectBox(item, []string{"Extract to a file", "Remove the attachment"}) {
+		case "Extract to a file":
+			var attachment Attachment
+			attachment, err = entry.Attachment(item)
+			if err == nil {
+				err = attachment.Extract(c.InputBox("File path", item, false))
+			}
+			if err != nil {
+				c.NotificationBox(fmt.Sprintf("%s", err), true)
+			} else {
+				c.NotificationBox(fmt.Sprintf("the attachment %s is extracted", item), false)
+			}
+			return false
+		case "Remove the attachment":
+			if c.ReadOnlyBox() || !c.ChoiceBox(fmt.Sprintf("Do you want remove the attachment %s ?", item), false) {
+				return false
+			}
+			err = c.Wallet.DeleteAttachment(entry.ID, item)
+		default:
+			return false
+		}
+	}
+
+	if err == nil {
+		err = c.Wallet.Save()
+	}
+	if err != nil {
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+		return false
+	}
+
+	return true
+}
+
+// ExpireBox to set the expiration date and the rotation policy of the password
+func (c *Cli) ExpireBox(entry *Entry) {
+	date := ""
+	if entry.Expire > 0 {
+		date = time.Unix(entry.Expire, 0).Format(ExpireDateFormat)
+	}
+
+	for {
+		expire, err := ParseExpire(c.InputBox("Expiration date (YYYY-MM-DD)", date, false))
+		if err == nil {
+			entry.Expire = expire
+			break
+		}
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+	}
+
+	days := ""
+	if entry.RotateDays > 0 {
+		days = strconv.Itoa(entry.RotateDays)
+	}
+
+	for {
+		days = c.InputBox("Rotate the password every N days", days, false)
+		if days == "" {
+			entry.RotateDays = 0
+			break
+		}
+		rotate, err := strconv.Atoi(days)
+		if err == nil && rotate >= 0 {
+			entry.RotateDays = rotate
+			break
+		}
+		c.NotificationBox("the rotation period must be a number of days", true)
+	}
+}
+
 // HelpBox print help message
 func (c *Cli) HelpBox() {
 	p := widgets.NewParagraph()
-	p.SetRect(25, 0, 80, 20)
+	p.SetRect(25, 0, 80, 23)
 	p.Title = "Short cuts"
 	p.Text = `[<escape>](fg:yellow)    clear current search
 [<enter> ](fg:yellow)    select entry or group
@@ -212,24 +842,146 @@ func (c *Cli) HelpBox() {
 [h       ](fg:yellow)    print this help message
 [q       ](fg:yellow)    quit
 [g       ](fg:yellow)    filter the entries by group
+[t       ](fg:yellow)    filter the entries by tag
+[e       ](fg:yellow)    filter the expired entries
 [n       ](fg:yellow)    add a new entry
 [u       ](fg:yellow)    update an entry
-[d       ](fg:yellow)    delete an entry
+[d       ](fg:yellow)    move an entry to the trash
+[b       ](fg:yellow)    view the trash
+[v       ](fg:yellow)    view the entry history
+[p       ](fg:yellow)    change the wallet passphrase
+[a       ](fg:yellow)    audit the wallet
 [/       ](fg:yellow)    search
 [Ctrl + b](fg:yellow)    copy username
 [Ctrl + c](fg:yellow)    copy password
 [Ctrl + o](fg:yellow)    copy OTP code
+[c       ](fg:yellow)    copy a custom field
+[f       ](fg:yellow)    manage the attachments
 `
 	ui.Render(p)
 
 }
 
+// PasswordBox to choose how to define the password
+func (c *Cli) PasswordBox(password string) string {
+	choices := []string{"Generate a random password"}
+	for _, profile := range c.Config.PasswordProfiles {
+		choices = append(choices, fmt.Sprintf("Generate a random password with the profile %s", profile.Name))
+	}
+	choices = append(choices, "Generate a random passphrase", "Enter a password")
+	if password != "" {
+		choices = append([]string{"Keep the current password"}, choices...)
+	}
+
+	choice := c.SelectBox("Password", choices)
+	for _, profile := range c.Config.PasswordProfiles {
+		if choice != fmt.Sprintf("Generate a random password with the profile %s", profile.Name) {
+			continue
+		}
+
+		newPassword, err := profile.Generate()
+		if err != nil {
+			c.NotificationBox(fmt.Sprintf("%s", err), true)
+			return password
+		}
+		return newPassword
+	}
+
+	switch choice {
+	case "Generate a random password":
+		return RandomString(c.Config.PasswordLength,
+			c.Config.PasswordLetter, c.Config.PasswordDigit, c.Config.PasswordSpecial)
+	case "Generate a random passphrase":
+		return RandomPassphrase(c.Config.PassphraseWords,
+			c.Config.PassphraseSeparator, c.Config.PassphraseCapitalize, c.Config.PassphraseDigit)
+	case "Enter a password":
+		return c.PasswordInputBox("Password", "")
+	}
+
+	return password
+}
+
 // UnlockWallet to decrypt a wallet
 func (c *Cli) UnlockWallet(wallet string) error {
+	ui.Clear()
+	err := c.NewWallet(wallet)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if c.Passphrase != "" {
+			c.Wallet.Passphrase = c.Passphrase
+		} else {
+			c.Wallet.Passphrase = c.InputBox("Passphrase to unlock the wallet", "", true)
+		}
+
+		err = c.Wallet.Load()
+		var lockErr *LockError
+		if errors.As(err, &lockErr) && c.ChoiceBox(fmt.Sprintf("%s, open it in read-only ?", err), true) {
+			c.Wallet.ReadOnly = true
+			err = c.Wallet.Load()
+		}
+		if err == nil || c.Passphrase != "" {
+			return err
+		}
+		c.NotificationBox(fmt.Sprintf("%s", err), true)
+	}
+
+	return err
+}
+
+// OpenWallet open the wallet without the interface, the passphrase is read
+// from the terminal or the standard input and the conflicts are resolved
+// with the newest entries
+func (c *Cli) OpenWallet(wallet string, readOnly bool) error {
+	err := c.NewWallet(wallet)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(c.Wallet.Path); err != nil {
+		return fmt.Errorf("the wallet %s doesn't exist", c.Wallet.Name)
+	}
+
+	c.Wallet.ReadOnly = readOnly
+	c.Wallet.Resolve = nil
+	c.Wallet.Passphrase, err = c.SourcePassphrase()
+	if err == nil && c.Wallet.Passphrase == "" {
+		c.Wallet.Passphrase, err = ReadPassphrase("Passphrase to unlock the wallet: ", os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Wallet.Load()
+}
+
+// SourcePassphrase read the passphrase from the source given by the flag or
+// the config, the warnings are printed on stderr, the passphrase is empty
+// if it must be prompted
+func (c *Cli) SourcePassphrase() (string, error) {
+	source := c.Config.PassphraseSource
+	if *SOURCE != "" {
+		source = *SOURCE
+	}
+	if source == "" || source == SourcePrompt {
+		return "", nil
+	}
+
+	passphrase, warnings, err := ReadPassphraseSource(source, c.Config.PassphraseCommand)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	return passphrase, err
+}
+
+// NewWallet prepare the wallet with the config
+func (c *Cli) NewWallet(wallet string) error {
 	var walletName string
 	var err error
 
-	ui.Clear()
 	if wallet == "" {
 		walletName = c.Config.WalletDefault
 	} else {
@@ -237,26 +989,69 @@ func (c *Cli) UnlockWallet(wallet string) error {
 	}
 
 	c.Wallet = Wallet{
-		Name: walletName,
-		Path: fmt.Sprintf("%s/%s.gpm", c.Config.WalletDir, walletName),
+		Name:       walletName,
+		Path:       fmt.Sprintf("%s/%s.gpm", c.Config.WalletDir, walletName),
+		MaxBackups: c.Config.WalletBackups,
+		MaxHistory: c.Config.EntryHistory,
+		Retention:  c.Config.TrashRetention,
+		Resolve:    c.ConflictBox,
+	}
+	if c.Config.WalletGit {
+		c.Wallet.Git = &GitRepository{Path: c.Config.WalletDir, Remote: c.Config.GitRemote}
+		err = c.Wallet.Git.Init()
+		if err != nil {
+			return err
+		}
 	}
 
-	for i := 0; i < 3; i++ {
-		c.Wallet.Passphrase = c.InputBox("Passphrase to unlock the wallet", "", true)
+	return nil
+}
 
-		err = c.Wallet.Load()
-		if err == nil {
-			return nil
+// RestoreBackup to replace the wallet by a backup
+func (c *Cli) RestoreBackup() error {
+	var items []string
+
+	backups, err := c.Wallet.Backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("the wallet hasn't backup")
+	}
+
+	for i, backup := range backups {
+		date, _ := c.Wallet.BackupTime(backup)
+		items = append(items, fmt.Sprintf("%d. %s", i+1, date.Format("2006-01-02 15:04:05")))
+	}
+
+	item := c.SelectBox("Backup to restore", items)
+	for i, backup := range backups {
+		if items[i] == item && c.ChoiceBox(fmt.Sprintf("Do you want restore the backup %s ?", item), false) {
+			return c.Wallet.RestoreBackup(backup)
 		}
-		c.NotificationBox(fmt.Sprintf("%s", err), true)
 	}
 
-	return err
+	return nil
+}
+
+// ChangePassphrase to encrypt the wallet with a new passphrase
+func (c *Cli) ChangePassphrase() error {
+	if c.Wallet.ReadOnly {
+		return fmt.Errorf("the wallet is open in read-only mode")
+	}
+
+	oldPassphrase := c.InputBox("Current passphrase", "", true)
+	newPassphrase := c.InputBox("New passphrase", "", true)
+	if c.InputBox("Confirm the new passphrase", "", true) != newPassphrase {
+		return fmt.Errorf("the new passphrases are different")
+	}
+
+	return c.Wallet.ChangePassphrase(oldPassphrase, newPassphrase)
 }
 
 // DeleteEntry to delete an exisiting entry
 func (c *Cli) DeleteEntry(entry Entry) bool {
-	if !c.ChoiceBox("Do you want delete this entry ?", false) {
+	if c.ReadOnlyBox() || !c.ChoiceBox("Do you want move this entry to the trash ?", false) {
 		return false
 	}
 
@@ -277,6 +1072,10 @@ func (c *Cli) DeleteEntry(entry Entry) bool {
 
 // UpdateEntry to update an existing entry
 func (c *Cli) UpdateEntry(entry Entry) bool {
+	if c.ReadOnlyBox() {
+		return false
+	}
+
 	entry.Name = c.InputBox("Name", entry.Name, false)
 	if entry.Group == "" || c.ChoiceBox("Change the group ?", false) {
 		group := c.SelectBox("Group", append(c.Wallet.Groups(), "* Create new group *"))
@@ -286,15 +1085,16 @@ func (c *Cli) UpdateEntry(entry Entry) bool {
 			entry.Group = group
 		}
 	}
+	entry.Tags = ParseTags(c.InputBox("Tags (comma separated)", strings.Join(entry.Tags, ", "), false))
 	entry.URI = c.InputBox("URI", entry.URI, false)
 	entry.User = c.InputBox("Username", entry.User, false)
-	if c.ChoiceBox("Generate a new random password ?", false) {
-		entry.Password = RandomString(c.Config.PasswordLength,
-			c.Config.PasswordLetter, c.Config.PasswordDigit, c.Config.PasswordSpecial)
-	}
-	entry.Password = c.InputBox("Password", "", true)
+	entry.Password = c.PasswordBox(entry.Password)
+	c.ExpireBox(&entry)
 	entry.OTP = c.InputBox("OTP Key", entry.OTP, false)
 	entry.Comment = c.InputBox("Comment", entry.Comment, false)
+	if c.ChoiceBox("Change the custom fields ?", false) {
+		entry.Fields = c.FieldsBox(entry.Fields)
+	}
 
 	err := c.Wallet.UpdateEntry(entry)
 	if err != nil {
@@ -313,6 +1113,10 @@ func (c *Cli) UpdateEntry(entry Entry) bool {
 
 // AddEntry to add new entry
 func (c *Cli) AddEntry() bool {
+	if c.ReadOnlyBox() {
+		return false
+	}
+
 	entry := Entry{}
 	entry.GenerateID()
 	entry.Name = c.InputBox("Name", "", false)
@@ -322,16 +1126,16 @@ func (c *Cli) AddEntry() bool {
 	} else {
 		entry.Group = group
 	}
+	entry.Tags = ParseTags(c.InputBox("Tags (comma separated)", "", false))
 	entry.URI = c.InputBox("URI", "", false)
 	entry.User = c.InputBox("Username", "", false)
-	if c.ChoiceBox("Generate a random password ?", true) {
-		entry.Password = RandomString(c.Config.PasswordLength,
-			c.Config.PasswordLetter, c.Config.PasswordDigit, c.Config.PasswordSpecial)
-	} else {
-		entry.Password = c.InputBox("Password", "", true)
-	}
+	entry.Password = c.PasswordBox("")
+	c.ExpireBox(&entry)
 	entry.OTP = c.InputBox("OTP Key", "", false)
 	entry.Comment = c.InputBox("Comment", "", false)
+	if c.ChoiceBox("Add custom fields ?", false) {
+		entry.Fields = c.FieldsBox(entry.Fields)
+	}
 
 	err := c.Wallet.AddEntry(entry)
 	if err != nil {
@@ -350,12 +1154,13 @@ func (c *Cli) AddEntry() bool {
 
 // ListEntries to list all entries
 func (c *Cli) ListEntries(ch chan<- bool) {
-	var pattern, group string
+	var pattern, group, tag, jump string
 	var entries []Entry
 	var selected bool
 
 	refresh := true
 	noGroup := false
+	expiring := false
 	index := -1
 
 	l := widgets.NewList()
@@ -374,15 +1179,43 @@ func (c *Cli) ListEntries(ch chan<- bool) {
 		} else {
 			l.Title = "Group: All"
 		}
+		if tag != "" {
+			l.Title = fmt.Sprintf("%s, Tag: %s", l.Title, tag)
+		}
+		if expiring {
+			l.Title = fmt.Sprintf("%s, Expiring", l.Title)
+		}
+		if c.Wallet.ReadOnly {
+			l.Title = fmt.Sprintf("%s (read-only)", l.Title)
+		}
 
 		if refresh {
 			refresh = false
 			index = -1
-			entries = c.Wallet.SearchEntry(pattern, group, noGroup)
+			if tag != "" {
+				entries = c.Wallet.SearchEntry(pattern, group, noGroup, tag)
+			} else {
+				entries = c.Wallet.SearchEntry(pattern, group, noGroup)
+			}
+			if expiring {
+				entries = filterExpiring(entries, c.Config.ExpireWarning)
+			}
 			l.Rows = []string{}
-			for _, entry := range entries {
-				l.Rows = append(l.Rows, entry.Name)
+			for i, entry := range entries {
+				switch {
+				case entry.Expired(time.Now()):
+					l.Rows = append(l.Rows, fmt.Sprintf("[%s](fg:red)", entry.Name))
+				case entry.Expired(time.Now().AddDate(0, 0, c.Config.ExpireWarning)):
+					l.Rows = append(l.Rows, fmt.Sprintf("[%s](fg:magenta)", entry.Name))
+				default:
+					l.Rows = append(l.Rows, entry.Name)
+				}
+				if entry.ID == jump {
+					l.SelectedRow = i
+					index = i
+				}
 			}
+			jump = ""
 			ui.Clear()
 			c.NotificationBox("press h to view short cuts", false)
 		}
@@ -410,7 +1243,10 @@ func (c *Cli) ListEntries(ch chan<- bool) {
 			index = l.SelectedRow
 		case "<Escape>":
 			pattern = ""
-			group = "all"
+			tag = ""
+			expiring = false
+			group = ""
+			noGroup = false
 			refresh = true
 		case "n":
 			refresh = c.AddEntry()
@@ -422,6 +1258,41 @@ func (c *Cli) ListEntries(ch chan<- bool) {
 			if selected {
 				refresh = c.DeleteEntry(entries[index])
 			}
+		case "f":
+			if selected && c.AttachmentsBox(entries[index]) {
+				jump = entries[index].ID
+				refresh = true
+			}
+			ui.Clear()
+		case "b":
+			refresh = c.TrashBox()
+			ui.Clear()
+		case "v":
+			if selected {
+				if c.HistoryBox(entries[index]) {
+					jump = entries[index].ID
+					refresh = true
+				}
+				ui.Clear()
+			}
+		case "a":
+			jump = c.AuditBox()
+			if jump != "" {
+				pattern = ""
+				tag = ""
+				expiring = false
+				group = ""
+				noGroup = false
+			}
+			refresh = true
+		case "p":
+			err := c.ChangePassphrase()
+			ui.Clear()
+			if err != nil {
+				c.NotificationBox(fmt.Sprintf("%s", err), true)
+			} else {
+				c.NotificationBox("the passphrase has been changed", false)
+			}
 		case "/":
 			pattern = c.InputBox("Search", pattern, false)
 			refresh = true
@@ -433,6 +1304,12 @@ func (c *Cli) ListEntries(ch chan<- bool) {
 				noGroup = true
 			}
 			refresh = true
+		case "t":
+			tag = c.TagsBox()
+			refresh = true
+		case "e":
+			expiring = !expiring
+			refresh = true
 		case "j", "<Down>":
 			if len(entries) > 0 {
 				l.ScrollDown()
@@ -451,6 +1328,10 @@ func (c *Cli) ListEntries(ch chan<- bool) {
 				c.NotificationBox("the password is copied in clipboard", false)
 				clipboard.WriteAll(entries[index].Password)
 			}
+		case "c":
+			if selected {
+				c.CopyFieldBox(entries[index])
+			}
 		case "<C-o>":
 			if selected {
 				code, time, _ := entries[index].OTPCode()
@@ -503,30 +1384,315 @@ func (c *Cli) ExportWallet() error {
 	return nil
 }
 
+// PrintAudit print the audit findings in table, json or yaml
+func (c *Cli) PrintAudit(format string) error {
+	findings := c.Wallet.Audit(c.Config.AuditOptions())
+	if findings == nil {
+		findings = []AuditFinding{}
+	}
+
+	return WriteOutput(os.Stdout, format, findings, func(output io.Writer) error {
+		for _, finding := range findings {
+			fmt.Fprintf(output, "[%s] %s: %s\n", finding.Type, finding.EntryName, finding.Message)
+		}
+		return nil
+	})
+}
+
+// MergeWallets merge two wallet files, the conflicts are resolved by the user
+func (c *Cli) MergeWallets(paths []string) (MergeResult, error) {
+	var wallets []Wallet
+
+	if len(paths) != 2 {
+		return MergeResult{}, fmt.Errorf("you must give two wallet files to merge")
+	}
+	if *BASE != "" {
+		paths = append(paths, *BASE)
+	}
+
+	passphrase := c.Passphrase
+	if passphrase == "" {
+		passphrase = c.InputBox("Passphrase to unlock the wallets", "", true)
+	}
+	for _, path := range paths {
+		wallet := Wallet{Path: path, Passphrase: passphrase, ReadOnly: true}
+		_, err := os.Stat(path)
+		if err != nil {
+			return MergeResult{}, err
+		}
+		err = wallet.Load()
+		if err != nil {
+			return MergeResult{}, fmt.Errorf("%s: %s", path, err)
+		}
+		wallets = append(wallets, wallet)
+	}
+
+	var data []WalletData
+	for _, wallet := range wallets {
+		data = append(data, WalletData{Entries: wallet.Entries, Trash: wallet.Trash})
+	}
+
+	base := WalletData{
+		Entries: MergeBase(data[0].Entries, data[1].Entries),
+		Trash:   MergeBase(data[0].Trash, data[1].Trash),
+	}
+	if len(wallets) == 3 {
+		base = data[2]
+	}
+	result := mergeWalletData(base, data[0], data[1], c.ConflictBox)
+
+	output := Wallet{Path: paths[0], Passphrase: passphrase, MaxBackups: c.Config.WalletBackups}
+	if *MERGED != "" {
+		output.Path = *MERGED
+	}
+	defer output.Unlock()
+
+	err := output.Load()
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if output.Create == 0 {
+		output.Create = wallets[0].Create
+	}
+	output.Entries = result.Entries
+	output.Trash = result.Trash
+
+	return result, output.Save()
+}
+
+// PrintMerge print the changes merged in the first wallet
+func PrintMerge(result MergeResult) {
+	for _, entry := range result.Added {
+		fmt.Printf("[added] %s\n", entry.Name)
+	}
+	for _, entry := range result.Updated {
+		fmt.Printf("[updated] %s\n", entry.Name)
+	}
+	for _, entry := range result.Deleted {
+		fmt.Printf("[deleted] %s\n", entry.Name)
+	}
+	for _, conflict := range result.Conflicts {
+		fmt.Printf("[conflict] %s\n", conflict.Name())
+	}
+	fmt.Printf("%d added, %d updated, %d deleted, %d conflicts\n",
+		len(result.Added), len(result.Updated), len(result.Deleted), len(result.Conflicts))
+}
+
+// ManageAttachments attach, list, extract or remove the files of an entry
+func (c *Cli) ManageAttachments() error {
+	entry, err := c.Wallet.FindEntry(*ENTRY)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *ATTACH != "":
+		attachment, err := NewAttachment(*ATTACH, c.Config.AttachmentMaxSize)
+		if err != nil {
+			return err
+		}
+		err = c.Wallet.AddAttachment(entry.ID, attachment)
+		if err != nil {
+			return err
+		}
+		return c.Wallet.Save()
+	case *EXTRACT != "":
+		attachment, err := entry.Attachment(*EXTRACT)
+		if err != nil {
+			return err
+		}
+		path := flag.Arg(0)
+		if path == "" {
+			path = attachment.Name
+		}
+		return attachment.Extract(path)
+	case *DETACH != "":
+		err = c.Wallet.DeleteAttachment(entry.ID, *DETACH)
+		if err != nil {
+			return err
+		}
+		return c.Wallet.Save()
+	}
+
+	return nil
+}
+
+// ManageGroups rename, merge or delete the groups from the command line
+func (c *Cli) ManageGroups() (int, error) {
+	switch {
+	case *RENAME != "":
+		if flag.Arg(0) == "" {
+			return 0, fmt.Errorf("you must give the new group name as argument")
+		}
+		count, err := c.Wallet.RenameGroup(*RENAME, flag.Arg(0))
+		if err != nil {
+			return 0, err
+		}
+		return count, c.Wallet.Save()
+	case *MERGEG != "":
+		if flag.Arg(0) == "" {
+			return 0, fmt.Errorf("you must give the target group as argument")
+		}
+		count, err := c.Wallet.MergeGroups(strings.Split(*MERGEG, ","), flag.Arg(0))
+		if err != nil {
+			return 0, err
+		}
+		return count, c.Wallet.Save()
+	}
+
+	count, err := c.Wallet.DeleteGroup(*DELETEG, *TRASH)
+	if err != nil {
+		return 0, err
+	}
+	return count, c.Wallet.Save()
+}
+
+// PrintAttachments print the attachments of the entry
+func (c *Cli) PrintAttachments(format string) error {
+	entry, err := c.Wallet.FindEntry(*ENTRY)
+	if err != nil {
+		return err
+	}
+
+	return WriteOutput(os.Stdout, format, NewAttachmentsOutput(entry.Attachments), func(output io.Writer) error {
+		for _, attachment := range entry.Attachments {
+			fmt.Fprintf(output, "%s\t%s\t%s\n", attachment.Name, attachment.MIME, FormatSize(attachment.Size))
+		}
+		return nil
+	})
+}
+
+// PrintExpiring print the entries with a password expired or expiring soon
+// and return the number of entries
+func (c *Cli) PrintExpiring(days int, format string, secrets bool) (int, error) {
+	entries := c.Wallet.ExpiringEntries(days)
+
+	return len(entries), WriteOutput(os.Stdout, format, NewEntriesOutput(entries, secrets), func(output io.Writer) error {
+		now := time.Now()
+		for _, entry := range entries {
+			date := time.Unix(entry.ExpireDate(), 0).Format(ExpireDateFormat)
+			if entry.Expired(now) {
+				fmt.Fprintf(output, "[expired] %s: since %s\n", entry.Name, date)
+			} else {
+				fmt.Fprintf(output, "[expiring] %s: on %s\n", entry.Name, date)
+			}
+		}
+		return nil
+	})
+}
+
+// filterExpiring return the entries with a password expired or expiring soon
+func filterExpiring(entries []Entry, days int) []Entry {
+	var expiring []Entry
+
+	limit := time.Now().AddDate(0, 0, days)
+	for _, entry := range entries {
+		if entry.Expired(limit) {
+			expiring = append(expiring, entry)
+		}
+	}
+
+	return expiring
+}
+
+// printStrength print the password strength on stderr to keep stdout for the password
+func printStrength(password string) {
+	strength := PasswordStrength(password)
+	fmt.Fprintf(os.Stderr, "strength: %s (%.0f bits)\n", strength.Label(), strength.Entropy)
+}
+
 // Run the cli interface
 func Run() {
 	var c Cli
 
 	flag.Parse()
-	c.Config.Load(*CONFIG)
+	if err := c.Config.Load(*CONFIG); err != nil {
+		fmt.Printf("failed to load the config: %v\n", err)
+		os.Exit(2)
+	}
+	if *BREACH != "" {
+		c.Config.BreachPath = *BREACH
+	}
+
+	if command := FindCommand(flag.Arg(0)); command != nil {
+		os.Exit(c.RunCommand(command, flag.Args()[1:]))
+	}
 
 	if *HELP {
 		flag.PrintDefaults()
+		fmt.Println()
+		PrintCommands(os.Stdout)
 		os.Exit(1)
 	} else if *PASSWD {
-		fmt.Println(RandomString(*LENGTH, *LETTER, *DIGIT, *SPECIAL))
+		var err error
+		profile := PasswordProfile{Length: *LENGTH, Letter: *LETTER, Digit: *DIGIT, Special: *SPECIAL}
+		if *PROFILE != "" {
+			profile, err = c.Config.Profile(*PROFILE)
+		}
+
+		password := ""
+		if err == nil {
+			password, err = profile.Generate()
+		}
+		if err != nil {
+			fmt.Printf("failed to generate a password: %v\n", err)
+			os.Exit(2)
+		}
+
+		fmt.Println(password)
+		printStrength(password)
+		os.Exit(0)
+	} else if *PHRASE {
+		words := c.Config.PassphraseWords
+		separator := c.Config.PassphraseSeparator
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "words":
+				words = *WORDS
+			case "separator":
+				separator = *SEP
+			}
+		})
+		if words < 1 {
+			fmt.Printf("failed to generate a passphrase: the number of words must be at least 1\n")
+			os.Exit(2)
+		}
+		passphrase := RandomPassphrase(words, separator,
+			*CAPITAL || c.Config.PassphraseCapitalize, *DIGIT || c.Config.PassphraseDigit)
+		fmt.Println(passphrase)
+		printStrength(passphrase)
 		os.Exit(0)
 	}
 
+	passphrase, err := c.SourcePassphrase()
+	if err != nil {
+		fmt.Printf("failed to open the wallet: %v\n", err)
+		os.Exit(2)
+	}
+	c.Passphrase = passphrase
+
 	if err := ui.Init(); err != nil {
 		fmt.Printf("failed to initialize termui: %v\n", err)
 		os.Exit(2)
 	}
 	defer ui.Close()
 
-	err := c.UnlockWallet(*WALLET)
+	if *MERGE {
+		result, err := c.MergeWallets(flag.Args())
+		ui.Close()
+		if err != nil {
+			fmt.Printf("failed to merge the wallets: %v\n", err)
+			os.Exit(2)
+		}
+		PrintMerge(result)
+		os.Exit(0)
+	}
+
+	err = c.UnlockWallet(*WALLET)
+	defer c.Wallet.Unlock()
 	if err != nil {
 		ui.Close()
+		c.Wallet.Unlock()
 		fmt.Printf("failed to open the wallet: %v\n", err)
 		os.Exit(2)
 	}
@@ -535,6 +1701,7 @@ func Run() {
 		err := c.ImportWallet()
 		if err != nil {
 			ui.Close()
+			c.Wallet.Unlock()
 			fmt.Printf("failed to import: %v\n", err)
 			os.Exit(2)
 		}
@@ -542,9 +1709,67 @@ func Run() {
 		err := c.ExportWallet()
 		if err != nil {
 			ui.Close()
+			c.Wallet.Unlock()
 			fmt.Printf("failed to export: %v\n", err)
 			os.Exit(2)
 		}
+	} else if *AUDIT {
+		ui.Close()
+		c.Wallet.Unlock()
+		err := c.PrintAudit(*OUTPUT)
+		if err != nil {
+			fmt.Printf("failed to audit the wallet: %v\n", err)
+			os.Exit(2)
+		}
+		os.Exit(0)
+	} else if *RESTORE {
+		err := c.RestoreBackup()
+		if err != nil {
+			ui.Close()
+			c.Wallet.Unlock()
+			fmt.Printf("failed to restore the backup: %v\n", err)
+			os.Exit(2)
+		}
+	} else if *ENTRY != "" {
+		err := c.ManageAttachments()
+		ui.Close()
+		if err == nil && (*FILES || *ATTACH == "" && *EXTRACT == "" && *DETACH == "") {
+			err = c.PrintAttachments(*OUTPUT)
+		}
+		c.Wallet.Unlock()
+		if err != nil {
+			fmt.Printf("failed to manage the attachments: %v\n", err)
+			os.Exit(2)
+		}
+		os.Exit(0)
+	} else if *RENAME != "" || *MERGEG != "" || *DELETEG != "" {
+		count, err := c.ManageGroups()
+		ui.Close()
+		c.Wallet.Unlock()
+		if err != nil {
+			fmt.Printf("failed to update the groups: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Printf("%d entries have been updated\n", count)
+		os.Exit(0)
+	} else if *SYNC {
+		result, err := c.Wallet.Sync()
+		ui.Close()
+		c.Wallet.Unlock()
+		if err != nil {
+			fmt.Printf("failed to sync the wallet: %v\n", err)
+			os.Exit(2)
+		}
+		PrintMerge(result)
+		os.Exit(0)
+	} else if *REKEY {
+		err := c.ChangePassphrase()
+		if err != nil {
+			ui.Close()
+			c.Wallet.Unlock()
+			fmt.Printf("failed to change the passphrase: %v\n", err)
+			os.Exit(2)
+		}
 	} else {
 		c1 := make(chan bool)
 		go c.ListEntries(c1)
@@ -0,0 +1,710 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of the commands
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitWallet   = 4
+	ExitExpiring = 5
+)
+
+// Command is a subcommand working without the interface, for the scripts
+type Command struct {
+	Name  string
+	Args  string
+	Usage string
+	Run   func(c *Cli, fs *flag.FlagSet, args []string) error
+}
+
+// Commands is the list of the subcommands
+var Commands = []Command{
+	{"list", "[pattern]", "list the entries", (*Cli).ListCommand},
+	{"show", "<name|id>", "print an entry, without its secrets by default", (*Cli).ShowCommand},
+	{"get", "<name|id>", "print a field of an entry, the password by default", (*Cli).GetCommand},
+	{"otp", "<name|id>", "print the OTP code of an entry", (*Cli).OTPCommand},
+	{"groups", "", "list the groups with their number of entries", (*Cli).GroupsCommand},
+	{"audit", "", "print a security report of the wallet", (*Cli).AuditCommand},
+	{"expiring", "", "print the entries with an expired password or expiring soon", (*Cli).ExpiringCommand},
+	{"add", "", "add an entry and print its ID", (*Cli).AddCommand},
+	{"edit", "<name|id>", "update an entry", (*Cli).EditCommand},
+	{"rm", "<name|id>", "move an entry to the trash", (*Cli).RemoveCommand},
+}
+
+// CommandError is an error with the exit code of the command, the message
+// is already printed if the error is nil
+type CommandError struct {
+	Code int
+	Err  error
+}
+
+// Error return the error message
+func (e *CommandError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+// FindCommand return the subcommand with this name, nil if it doesn't exist
+func FindCommand(name string) *Command {
+	for index := range Commands {
+		if Commands[index].Name == name {
+			return &Commands[index]
+		}
+	}
+
+	return nil
+}
+
+// PrintCommands print the list of the subcommands
+func PrintCommands(output io.Writer) {
+	fmt.Fprintln(output, "Commands:")
+	for _, command := range Commands {
+		fmt.Fprintf(output, "  %s %s\n    \t%s\n", command.Name, command.Args, command.Usage)
+	}
+}
+
+// RunCommand run a subcommand and return the exit code
+func (c *Cli) RunCommand(command *Command, args []string) int {
+	fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gpm [options] %s [flags] %s\n", command.Name, command.Args)
+		fs.PrintDefaults()
+	}
+
+	err := command.Run(c, fs, args)
+	c.Wallet.Unlock()
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) && commandErr.Err == nil {
+		return commandErr.Code
+	}
+
+	fmt.Fprintf(os.Stderr, "gpm %s: %s\n", command.Name, err)
+	if commandErr != nil {
+		return commandErr.Code
+	}
+
+	return ExitError
+}
+
+// parseArgs parse the flags placed before or after the arguments, the
+// arguments after -- aren't parsed
+func parseArgs(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	var arguments []string
+
+	for {
+		err := fs.Parse(args)
+		if err == flag.ErrHelp {
+			return nil, err
+		}
+		if err != nil {
+			return nil, &CommandError{Code: ExitUsage}
+		}
+
+		parsed := len(args) - fs.NArg()
+		if fs.NArg() == 0 || parsed > 0 && args[parsed-1] == "--" {
+			arguments = append(arguments, fs.Args()...)
+			break
+		}
+		arguments = append(arguments, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(arguments) < min || len(arguments) > max {
+		fmt.Fprintf(fs.Output(), "wrong number of arguments\n")
+		fs.Usage()
+		return nil, &CommandError{Code: ExitUsage}
+	}
+
+	return arguments, nil
+}
+
+// openCommandWallet open the wallet given with the global flag
+func (c *Cli) openCommandWallet(readOnly bool) error {
+	err := c.OpenWallet(*WALLET, readOnly)
+	if err != nil {
+		return &CommandError{Code: ExitWallet, Err: err}
+	}
+
+	return nil
+}
+
+// outputFlags define the flags of the output format and of the secrets
+func outputFlags(fs *flag.FlagSet) (*string, *bool) {
+	format := fs.String("output", *OUTPUT, "output format: table, json or yaml")
+	secrets := fs.Bool("show-secrets", *SECRETS, "print the password, the OTP key and the secret fields")
+
+	return format, secrets
+}
+
+// checkCommandOutput return an usage error if the output format is unknown
+func checkCommandOutput(format string) error {
+	err := CheckOutput(format)
+	if err != nil {
+		return &CommandError{Code: ExitUsage, Err: err}
+	}
+
+	return nil
+}
+
+// findCommandEntry return the entry with this name or ID
+func (c *Cli) findCommandEntry(nameOrID string) (Entry, error) {
+	entry, err := c.Wallet.FindEntry(nameOrID)
+	if err != nil {
+		return entry, &CommandError{Code: ExitNotFound, Err: err}
+	}
+
+	return entry, nil
+}
+
+// saveCommandWallet save the wallet after a change
+func (c *Cli) saveCommandWallet() error {
+	err := c.Wallet.Save()
+	if err != nil {
+		return &CommandError{Code: ExitWallet, Err: err}
+	}
+
+	return nil
+}
+
+// ListCommand print the entries matching the pattern
+func (c *Cli) ListCommand(fs *flag.FlagSet, args []string) error {
+	group := fs.String("group", "", "list only the entries of the group and of its sub groups")
+	tag := fs.String("tag", "", "list only the entries with the tag")
+	format, secrets := outputFlags(fs)
+	args, err := parseArgs(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	pattern := ""
+	if len(args) == 1 {
+		pattern = args[0]
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return &CommandError{Code: ExitUsage, Err: fmt.Errorf("the pattern isn't a valid regexp: %s", err)}
+	}
+
+	var tags []string
+	if *tag != "" {
+		tags = append(tags, *tag)
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	entries := c.Wallet.SearchEntry(pattern, *group, false, tags...)
+	return WriteOutput(os.Stdout, *format, NewEntriesOutput(entries, *secrets), func(output io.Writer) error {
+		table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "ID\tNAME\tGROUP\tUSER\tURI")
+		for _, entry := range entries {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.Name, entry.Group, entry.User, entry.URI)
+		}
+		return table.Flush()
+	})
+}
+
+// ShowCommand print an entry, the secrets are masked unless requested
+func (c *Cli) ShowCommand(fs *flag.FlagSet, args []string) error {
+	format, secrets := outputFlags(fs)
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findCommandEntry(args[0])
+	if err != nil {
+		return err
+	}
+
+	return WriteOutput(os.Stdout, *format, NewEntryOutput(entry, *secrets), func(output io.Writer) error {
+		printEntry(output, entry, *secrets)
+		return nil
+	})
+}
+
+// printEntry print the details of an entry, the secrets are masked unless requested
+func printEntry(output io.Writer, entry Entry, secrets bool) {
+	fmt.Fprintf(output, "Name: %s\n", entry.Name)
+	fmt.Fprintf(output, "ID: %s\n", entry.ID)
+	fmt.Fprintf(output, "Group: %s\n", entry.Group)
+	if len(entry.Tags) > 0 {
+		fmt.Fprintf(output, "Tags: %s\n", strings.Join(entry.Tags, ", "))
+	}
+	fmt.Fprintf(output, "URI: %s\n", entry.URI)
+	fmt.Fprintf(output, "User: %s\n", entry.User)
+	if entry.Password != "" && secrets {
+		fmt.Fprintf(output, "Password: %s\n", entry.Password)
+	} else if entry.Password != "" {
+		fmt.Fprintf(output, "Password: %s\n", strings.Repeat("*", 8))
+	}
+	if expire := entry.ExpireDate(); expire > 0 {
+		fmt.Fprintf(output, "Expire: %s\n", time.Unix(expire, 0).Format(ExpireDateFormat))
+	}
+	switch {
+	case entry.OTP == "":
+		fmt.Fprintln(output, "OTP: no")
+	case secrets:
+		fmt.Fprintf(output, "OTP: %s\n", entry.OTP)
+	default:
+		fmt.Fprintln(output, "OTP: yes")
+	}
+	fmt.Fprintf(output, "Comment: %s\n", entry.Comment)
+	for _, field := range entry.Fields {
+		if secrets {
+			fmt.Fprintf(output, "%s: %s\n", field.Name, field.Value)
+		} else {
+			fmt.Fprintf(output, "%s: %s\n", field.Name, field.Display())
+		}
+	}
+	for _, attachment := range entry.Attachments {
+		fmt.Fprintf(output, "Attachment: %s (%s)\n", attachment.Name, FormatSize(attachment.Size))
+	}
+}
+
+// GetCommand print the value of a field of an entry
+func (c *Cli) GetCommand(fs *flag.FlagSet, args []string) error {
+	name := fs.String("field", "password", "name of the field: name, id, uri, user, password, otp, group, comment, tags or a custom field")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findCommandEntry(args[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := entry.Value(*name)
+	if err != nil {
+		return &CommandError{Code: ExitNotFound, Err: err}
+	}
+
+	fmt.Println(value)
+	return nil
+}
+
+// OTPCommand print the current OTP code of an entry
+func (c *Cli) OTPCommand(fs *flag.FlagSet, args []string) error {
+	format := fs.String("output", *OUTPUT, "output format: table, json or yaml")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findCommandEntry(args[0])
+	if err != nil {
+		return err
+	}
+
+	otp, err := NewOTPOutput(entry)
+	if err != nil {
+		return &CommandError{Code: ExitNotFound, Err: err}
+	}
+
+	return WriteOutput(os.Stdout, *format, otp, func(output io.Writer) error {
+		_, err := fmt.Fprintln(output, otp.Code)
+		return err
+	})
+}
+
+// GroupsCommand print the groups tree with the number of entries
+func (c *Cli) GroupsCommand(fs *flag.FlagSet, args []string) error {
+	format := fs.String("output", *OUTPUT, "output format: table, json or yaml")
+	_, err := parseArgs(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	groups := c.Wallet.GroupsOutput()
+	return WriteOutput(os.Stdout, *format, groups, func(output io.Writer) error {
+		table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "GROUP\tENTRIES")
+		for _, group := range groups {
+			fmt.Fprintf(table, "%s\t%d\n", group.Name, group.Entries)
+		}
+		return table.Flush()
+	})
+}
+
+// AuditCommand print the security report of the wallet
+func (c *Cli) AuditCommand(fs *flag.FlagSet, args []string) error {
+	format := fs.String("output", *OUTPUT, "output format: table, json or yaml")
+	_, err := parseArgs(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	return c.PrintAudit(*format)
+}
+
+// ExpiringCommand print the entries expired or expiring in the next days,
+// the exit code is ExitExpiring if there is at least one entry
+func (c *Cli) ExpiringCommand(fs *flag.FlagSet, args []string) error {
+	days := fs.Int("days", c.Config.ExpireWarning, "specify the number of days before the expiration to print an entry")
+	format, secrets := outputFlags(fs)
+	_, err := parseArgs(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	err = checkCommandOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(true)
+	if err != nil {
+		return err
+	}
+
+	count, err := c.PrintExpiring(*days, *format, *secrets)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &CommandError{Code: ExitExpiring}
+	}
+
+	return nil
+}
+
+// AddCommand add an entry and print its ID
+func (c *Cli) AddCommand(fs *flag.FlagSet, args []string) error {
+	options := newEntryFlags(fs)
+	_, err := parseArgs(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(false)
+	if err != nil {
+		return err
+	}
+
+	entry := Entry{}
+	entry.GenerateID()
+	err = options.apply(c, fs, &entry)
+	if err != nil {
+		return err
+	}
+
+	err = c.Wallet.AddEntry(entry)
+	if err != nil {
+		return &CommandError{Code: ExitUsage, Err: err}
+	}
+
+	err = c.saveCommandWallet()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(entry.ID)
+	return nil
+}
+
+// EditCommand update the values given with the flags
+func (c *Cli) EditCommand(fs *flag.FlagSet, args []string) error {
+	options := newEntryFlags(fs)
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(false)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findCommandEntry(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = options.apply(c, fs, &entry)
+	if err != nil {
+		return err
+	}
+
+	err = c.Wallet.UpdateEntry(entry)
+	if err != nil {
+		return &CommandError{Code: ExitUsage, Err: err}
+	}
+
+	return c.saveCommandWallet()
+}
+
+// RemoveCommand move an entry to the trash
+func (c *Cli) RemoveCommand(fs *flag.FlagSet, args []string) error {
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	err = c.openCommandWallet(false)
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.findCommandEntry(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = c.Wallet.DeleteEntry(entry.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.saveCommandWallet()
+}
+
+// fieldsFlag is a repeatable flag with a custom field name[:type]=value
+type fieldsFlag []CustomField
+
+// String return the value of the flag
+func (f *fieldsFlag) String() string {
+	return ""
+}
+
+// Set add a custom field
+func (f *fieldsFlag) Set(value string) error {
+	field, err := ParseField(value)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, field)
+	return nil
+}
+
+// listFlag is a repeatable string flag
+type listFlag []string
+
+// String return the value of the flag
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set add a value
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// entryFlags are the flags of the entry values for the add and edit commands
+type entryFlags struct {
+	name          *string
+	group         *string
+	tags          *string
+	uri           *string
+	user          *string
+	comment       *string
+	expire        *string
+	rotate        *int
+	passwordStdin *bool
+	generate      *bool
+	profile       *string
+	fields        fieldsFlag
+	deleteFields  listFlag
+}
+
+// newEntryFlags define the flags of the entry values
+func newEntryFlags(fs *flag.FlagSet) *entryFlags {
+	options := entryFlags{
+		name:          fs.String("name", "", "name of the entry"),
+		group:         fs.String("group", "", "group of the entry"),
+		tags:          fs.String("tags", "", "comma separated tags of the entry"),
+		uri:           fs.String("uri", "", "uri of the entry"),
+		user:          fs.String("user", "", "username of the entry"),
+		comment:       fs.String("comment", "", "comment of the entry"),
+		expire:        fs.String("expire", "", "expiration date of the password (YYYY-MM-DD), empty to remove it"),
+		rotate:        fs.Int("rotate", 0, "change the password every N days, 0 to disable the rotation"),
+		passwordStdin: fs.Bool("password-stdin", false, "read the password from the standard input, on the line after the passphrase if it is read from the standard input too"),
+		generate:      fs.Bool("generate", false, "generate a random password with the config"),
+		profile:       fs.String("profile", "", "generate a random password with a password profile from the config"),
+	}
+	fs.Var(&options.fields, "field", "set a custom field name[:type]=value, can be repeated")
+	fs.Var(&options.deleteFields, "delete-field", "remove a custom field, can be repeated")
+
+	return &options
+}
+
+// apply set the values of the flags given on the command line in the entry
+func (f *entryFlags) apply(c *Cli, fs *flag.FlagSet, entry *Entry) error {
+	var err error
+
+	fs.Visit(func(option *flag.Flag) {
+		switch option.Name {
+		case "name":
+			entry.Name = *f.name
+		case "group":
+			entry.Group = *f.group
+		case "tags":
+			entry.Tags = ParseTags(*f.tags)
+		case "uri":
+			entry.URI = *f.uri
+		case "user":
+			entry.User = *f.user
+		case "comment":
+			entry.Comment = *f.comment
+		case "rotate":
+			entry.RotateDays = *f.rotate
+		case "expire":
+			entry.Expire, err = ParseExpire(*f.expire)
+		}
+	})
+	if err != nil {
+		return &CommandError{Code: ExitUsage, Err: err}
+	}
+
+	entry.Fields = append([]CustomField(nil), entry.Fields...)
+	for _, name := range f.deleteFields {
+		if _, err := entry.Field(name); err != nil {
+			return &CommandError{Code: ExitNotFound, Err: err}
+		}
+		for index, field := range entry.Fields {
+			if strings.EqualFold(field.Name, name) {
+				entry.Fields = append(entry.Fields[:index], entry.Fields[index+1:]...)
+				break
+			}
+		}
+	}
+	entry.Fields = setFields(entry.Fields, f.fields)
+
+	password, err := f.password(c)
+	if err != nil {
+		return err
+	}
+	if password != "" {
+		entry.Password = password
+	}
+
+	return nil
+}
+
+// password return the new password read or generated, empty to keep it
+func (f *entryFlags) password(c *Cli) (string, error) {
+	switch {
+	case *f.passwordStdin && (*f.generate || *f.profile != ""), *f.generate && *f.profile != "":
+		return "", &CommandError{Code: ExitUsage, Err: fmt.Errorf("-password-stdin, -generate and -profile can't be used together")}
+	case *f.passwordStdin:
+		password, err := ReadPassphrase("Password: ", os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the password: %s", err)
+		}
+		if password == "" {
+			return "", fmt.Errorf("the password can't be empty")
+		}
+		return password, nil
+	case *f.generate:
+		return RandomString(c.Config.PasswordLength,
+			c.Config.PasswordLetter, c.Config.PasswordDigit, c.Config.PasswordSpecial), nil
+	case *f.profile != "":
+		profile, err := c.Config.Profile(*f.profile)
+		if err != nil {
+			return "", &CommandError{Code: ExitUsage, Err: err}
+		}
+		return profile.Generate()
+	}
+
+	return "", nil
+}
+
+// setFields replace the fields with the same name and append the new fields
+func setFields(fields []CustomField, values []CustomField) []CustomField {
+	for _, value := range values {
+		found := false
+		for index, field := range fields {
+			if strings.EqualFold(field.Name, value.Name) {
+				fields[index] = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, value)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,178 @@
+package gpm
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// generateCommandCli save a wallet with a duplicated entry name in dir, the
+// passphrase is read from the GPM_TEST_PASSPHRASE environment variable
+func generateCommandCli(t *testing.T, dir string) Cli {
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.AddEntry(Entry{ID: "10", Name: "Entry 1"})
+	err := wallet.Save()
+	if err != nil {
+		t.Fatalf("save wallet mustn't return an error: %s", err)
+	}
+	wallet.Unlock()
+
+	var c Cli
+	c.Config.WalletDir = dir
+	c.Config.WalletDefault = "test"
+	c.Config.EntryHistory = 10
+	c.Config.PassphraseSource = "env:GPM_TEST_PASSPHRASE"
+
+	return c
+}
+
+// runTestCommand run a command with the passphrase, the outputs are discarded
+func runTestCommand(c *Cli, passphrase string, name string, args ...string) int {
+	stdout, stderr := os.Stdout, os.Stderr
+	devNull, _ := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	os.Stdout, os.Stderr = devNull, devNull
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	}()
+
+	os.Setenv("GPM_TEST_PASSPHRASE", passphrase)
+	defer os.Unsetenv("GPM_TEST_PASSPHRASE")
+
+	return c.RunCommand(FindCommand(name), args)
+}
+
+// loadCommandWallet return the wallet saved by the commands
+func loadCommandWallet(t *testing.T, dir string) Wallet {
+	wallet := Wallet{Path: filepath.Join(dir, "test.gpm"), Passphrase: "secret"}
+	err := wallet.Load()
+	if err != nil {
+		t.Fatalf("load wallet mustn't return an error: %s", err)
+	}
+	wallet.Unlock()
+
+	return wallet
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	field := fs.String("field", "password", "")
+
+	args, err := parseArgs(fs, []string{"github", "-field", "user"}, 1, 1)
+	if err != nil {
+		t.Fatalf("the flags after the arguments mustn't return an error: %s", err)
+	}
+	if !reflect.DeepEqual(args, []string{"github"}) || *field != "user" {
+		t.Errorf("the flags after the arguments must be parsed: %v %s", args, *field)
+	}
+
+	args, err = parseArgs(fs, []string{"--", "-github"}, 1, 1)
+	if err != nil || !reflect.DeepEqual(args, []string{"-github"}) {
+		t.Errorf("the arguments after -- mustn't be parsed: %v %v", args, err)
+	}
+
+	_, err = parseArgs(fs, []string{"github", "gitlab"}, 1, 1)
+	if err == nil {
+		t.Error("too many arguments must return an error")
+	}
+
+	_, err = parseArgs(fs, []string{"github", "-unknown"}, 1, 1)
+	if err == nil {
+		t.Error("an unknown flag must return an error")
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	fields := []CustomField{{Name: "PIN", Type: FieldSecret, Value: "1234"}}
+	fields = setFields(fields, []CustomField{
+		{Name: "pin", Type: FieldSecret, Value: "5678"},
+		{Name: "Question", Type: FieldText, Value: "first pet"},
+	})
+
+	if len(fields) != 2 {
+		t.Fatalf("a new field must be appended: %d", len(fields))
+	}
+	if fields[0].Value != "5678" {
+		t.Errorf("a field with the same name must be replaced: %s", fields[0].Value)
+	}
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	c := generateCommandCli(t, dir)
+	tests := []struct {
+		passphrase string
+		command    string
+		args       []string
+		code       int
+	}{
+		{"secret", "get", []string{"Entry 2", "-field", "name"}, ExitOK},
+		{"secret", "list", []string{"-output", "json"}, ExitOK},
+		{"secret", "get", []string{}, ExitUsage},
+		{"secret", "get", []string{"Entry 2", "-unknown"}, ExitUsage},
+		{"secret", "list", []string{"-output", "xml"}, ExitUsage},
+		{"secret", "add", []string{"-user", "me"}, ExitUsage},
+		{"secret", "edit", []string{"Entry 2", "-name", ""}, ExitUsage},
+		{"secret", "get", []string{"Unknown"}, ExitNotFound},
+		{"secret", "get", []string{"Entry 1"}, ExitNotFound},
+		{"secret", "get", []string{"Entry 2", "-field", "unknown"}, ExitNotFound},
+		{"bad", "get", []string{"Entry 2"}, ExitWallet},
+	}
+
+	for _, test := range tests {
+		code := runTestCommand(&c, test.passphrase, test.command, test.args...)
+		if code != test.code {
+			t.Errorf("%s %v must exit with %d: %d", test.command, test.args, test.code, code)
+		}
+	}
+
+	*WALLET = "missing"
+	defer func() { *WALLET = "" }()
+	code := runTestCommand(&c, "secret", "list")
+	if code != ExitWallet {
+		t.Errorf("a missing wallet must exit with %d: %d", ExitWallet, code)
+	}
+}
+
+func TestRunCommandSaveWallet(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	c := generateCommandCli(t, dir)
+	code := runTestCommand(&c, "secret", "add", "-name", "github", "-user", "me")
+	if code != ExitOK {
+		t.Fatalf("add must exit with %d: %d", ExitOK, code)
+	}
+	wallet := loadCommandWallet(t, dir)
+	entry, err := wallet.FindEntry("github")
+	if err != nil || entry.User != "me" {
+		t.Fatalf("add must save the new entry: %v %s", entry, err)
+	}
+
+	code = runTestCommand(&c, "secret", "edit", "github", "-user", "you")
+	if code != ExitOK {
+		t.Fatalf("edit must exit with %d: %d", ExitOK, code)
+	}
+	wallet = loadCommandWallet(t, dir)
+	entry, _ = wallet.FindEntry(entry.ID)
+	if entry.User != "you" {
+		t.Errorf("edit must save the updated entry: %s", entry.User)
+	}
+
+	code = runTestCommand(&c, "secret", "rm", "github")
+	if code != ExitOK {
+		t.Fatalf("rm must exit with %d: %d", ExitOK, code)
+	}
+	wallet = loadCommandWallet(t, dir)
+	if _, err := wallet.FindEntry(entry.ID); err == nil || len(wallet.Trash) != 1 {
+		t.Errorf("rm must save the entry in the trash: %d", len(wallet.Trash))
+	}
+}
@@ -25,12 +25,30 @@ import (
 
 // Config struct contain the config
 type Config struct {
-	WalletDir       string `json:"wallet_dir"`
-	WalletDefault   string `json:"wallet_default"`
-	PasswordLength  int    `json:"password_length"`
-	PasswordLetter  bool   `json:"password_letter"`
-	PasswordDigit   bool   `json:"password_digit"`
-	PasswordSpecial bool   `json:"password_special"`
+	WalletDir            string `json:"wallet_dir"`
+	WalletDefault        string `json:"wallet_default"`
+	WalletBackups        int    `json:"wallet_backups"`
+	WalletGit            bool   `json:"wallet_git"`
+	GitRemote            string `json:"git_remote"`
+	EntryHistory         int    `json:"entry_history"`
+	TrashRetention       int    `json:"trash_retention"`
+	AttachmentMaxSize    int64  `json:"attachment_max_size"`
+	ExpireWarning        int    `json:"expire_warning"`
+	PasswordLength       int    `json:"password_length"`
+	PasswordLetter       bool   `json:"password_letter"`
+	PasswordDigit        bool   `json:"password_digit"`
+	PasswordSpecial      bool   `json:"password_special"`
+	PassphraseWords      int    `json:"passphrase_words"`
+	PassphraseSeparator  string `json:"passphrase_separator"`
+	PassphraseCapitalize bool   `json:"passphrase_capitalize"`
+	PassphraseDigit      bool   `json:"passphrase_digit"`
+	AuditMinScore        int    `json:"audit_min_score"`
+	AuditMaxAge          int    `json:"audit_max_age"`
+	BreachPath           string `json:"breach_path"`
+	PassphraseSource     string `json:"passphrase_source"`
+	PassphraseCommand    string `json:"passphrase_command"`
+
+	PasswordProfiles []PasswordProfile `json:"password_profiles"`
 }
 
 // Init the configuration
@@ -48,10 +66,22 @@ func (c *Config) Init() error {
 		c.WalletDir = fmt.Sprintf("%s/.config/gpm", user.HomeDir)
 	}
 	c.WalletDefault = "default"
+	c.WalletBackups = 5
+	c.EntryHistory = 10
+	c.TrashRetention = 30
+	c.AttachmentMaxSize = 1024 * 1024
+	c.ExpireWarning = 14
 	c.PasswordLength = 16
 	c.PasswordLetter = true
 	c.PasswordDigit = true
 	c.PasswordSpecial = false
+	c.PassphraseWords = 6
+	c.PassphraseSeparator = "-"
+	c.PassphraseCapitalize = false
+	c.PassphraseDigit = false
+	c.AuditMinScore = 3
+	c.AuditMaxAge = 365
+	c.PassphraseSource = SourcePrompt
 
 	return nil
 }
@@ -80,6 +110,10 @@ func (c *Config) Load(path string) error {
 		}
 	}
 
+	if c.PassphraseWords < 1 {
+		return fmt.Errorf("passphrase_words must be at least 1: %d", c.PassphraseWords)
+	}
+
 	err = os.MkdirAll(c.WalletDir, 0700)
 	if err != nil {
 		return err
@@ -88,6 +122,27 @@ func (c *Config) Load(path string) error {
 	return nil
 }
 
+// Profile return the password profile with this name
+func (c *Config) Profile(name string) (PasswordProfile, error) {
+	for _, profile := range c.PasswordProfiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+
+	return PasswordProfile{}, fmt.Errorf("the password profile %s doesn't exist", name)
+}
+
+// AuditOptions return the thresholds of the wallet audit
+func (c *Config) AuditOptions() AuditOptions {
+	options := AuditOptions{MinScore: c.AuditMinScore, MaxAge: c.AuditMaxAge}
+	if c.BreachPath != "" {
+		options.Breach = &BreachChecker{Path: c.BreachPath}
+	}
+
+	return options
+}
+
 // Save the configuration
 func (c *Config) Save(path string) error {
 	data, err := json.Marshal(&c)
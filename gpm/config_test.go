@@ -18,6 +18,30 @@ func TestInit(t *testing.T) {
 		t.Errorf("the WalletDefaut must be 'default': %s", config.WalletDefault)
 	}
 
+	if config.WalletBackups != 5 {
+		t.Errorf("the WalletBackups must be 5: %d", config.WalletBackups)
+	}
+
+	if config.EntryHistory != 10 {
+		t.Errorf("the EntryHistory must be 10: %d", config.EntryHistory)
+	}
+
+	if config.TrashRetention != 30 {
+		t.Errorf("the TrashRetention must be 30: %d", config.TrashRetention)
+	}
+
+	if config.AttachmentMaxSize != 1048576 {
+		t.Errorf("the AttachmentMaxSize must be 1048576: %d", config.AttachmentMaxSize)
+	}
+
+	if config.ExpireWarning != 14 {
+		t.Errorf("the ExpireWarning must be 14: %d", config.ExpireWarning)
+	}
+
+	if config.PassphraseSource != SourcePrompt {
+		t.Errorf("the PassphraseSource must be prompt: %s", config.PassphraseSource)
+	}
+
 	if config.PasswordLength != 16 {
 		t.Errorf("the PasswordLength must be 16: %d", config.PasswordLength)
 	}
@@ -33,6 +57,14 @@ func TestInit(t *testing.T) {
 	if config.PasswordSpecial != false {
 		t.Error("the PasswordSpecial must be false")
 	}
+
+	if config.PassphraseWords != 6 {
+		t.Errorf("the PassphraseWords must be 6: %d", config.PassphraseWords)
+	}
+
+	if config.PassphraseSeparator != "-" {
+		t.Errorf("the PassphraseSeparator must be '-': %s", config.PassphraseSeparator)
+	}
 }
 
 func TestSave(t *testing.T) {
@@ -70,3 +102,38 @@ func TestLoadWithoutFile(t *testing.T) {
 		t.Errorf("load config without file mustn't return an error: %s", err)
 	}
 }
+
+func TestLoadWithBadPassphraseWords(t *testing.T) {
+	var config Config
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+
+	config.Init()
+	config.PassphraseWords = 0
+	config.Save(tmpFile.Name())
+	err := config.Load(tmpFile.Name())
+	if err == nil {
+		t.Error("load config with 0 passphrase words must return an error")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	var config Config
+
+	config.Init()
+	config.PasswordProfiles = []PasswordProfile{{Name: "pin", Length: 6, Digit: true}}
+
+	_, err := config.Profile("bad")
+	if err == nil {
+		t.Error("a profile which doesn't exist must return an error")
+	}
+
+	profile, err := config.Profile("pin")
+	if err != nil {
+		t.Errorf("a profile which exists mustn't return an error: %s", err)
+	}
+	if profile.Length != 6 {
+		t.Errorf("the profile length must be 6: %d", profile.Length)
+	}
+}
@@ -20,16 +20,64 @@ import (
 	"crypto/rand"
 	"crypto/sha512"
 	"encoding/base64"
+	"fmt"
 	"io"
-	mrand "math/rand"
-	"time"
+	"math/big"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 )
 
-// Encrypt data with aes256
-func Encrypt(data []byte, passphrase string, salt string) (string, error) {
-	key := pbkdf2.Key([]byte(passphrase), []byte(salt), 4096, 32, sha512.New)
+// Key derivation functions
+const (
+	KDFArgon2id = "argon2id"
+	KDFPBKDF2   = "pbkdf2-sha512"
+)
+
+// KDF contains the key derivation function and its parameters
+type KDF struct {
+	Name        string
+	Time        uint32 `json:",omitempty"`
+	Memory      uint32 `json:",omitempty"`
+	Parallelism uint8  `json:",omitempty"`
+	Iterations  int    `json:",omitempty"`
+}
+
+// DefaultKDF return the key derivation function used for the new wallets
+func DefaultKDF() KDF {
+	return KDF{Name: KDFArgon2id, Time: 3, Memory: 64 * 1024, Parallelism: 4}
+}
+
+// LegacyKDF return the key derivation function used by the old wallets
+func LegacyKDF() KDF {
+	return KDF{Name: KDFPBKDF2, Iterations: 4096}
+}
+
+// Key derive an aes256 key from the passphrase and the salt
+func (k *KDF) Key(passphrase string, salt string) ([]byte, error) {
+	switch k.Name {
+	case KDFArgon2id:
+		if k.Time == 0 || k.Memory == 0 || k.Parallelism == 0 {
+			return []byte{}, fmt.Errorf("the argon2id parameters are invalid")
+		}
+		return argon2.IDKey([]byte(passphrase), []byte(salt), k.Time, k.Memory, k.Parallelism, 32), nil
+	case KDFPBKDF2:
+		if k.Iterations <= 0 {
+			return []byte{}, fmt.Errorf("the pbkdf2 parameters are invalid")
+		}
+		return pbkdf2.Key([]byte(passphrase), []byte(salt), k.Iterations, 32, sha512.New), nil
+	}
+
+	return []byte{}, fmt.Errorf("unknown key derivation function: %s", k.Name)
+}
+
+// Encrypt data with aes256, the additional data is authenticated but not encrypted
+func Encrypt(data []byte, passphrase string, salt string, kdf KDF, additionalData []byte) (string, error) {
+	key, err := kdf.Key(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
 
 	block, err := aes.NewCipher([]byte(key))
 	if err != nil {
@@ -47,14 +95,17 @@ func Encrypt(data []byte, passphrase string, salt string) (string, error) {
 		return "", err
 	}
 
-	dataEncrypted := cipher.Seal(nonce, nonce, data, nil)
+	dataEncrypted := cipher.Seal(nonce, nonce, data, additionalData)
 
 	return base64.StdEncoding.EncodeToString(dataEncrypted), nil
 }
 
-// Decrypt data
-func Decrypt(data string, passphrase string, salt string) ([]byte, error) {
-	key := pbkdf2.Key([]byte(passphrase), []byte(salt), 4096, 32, sha512.New)
+// Decrypt data and verify the additional data
+func Decrypt(data string, passphrase string, salt string, kdf KDF, additionalData []byte) ([]byte, error) {
+	key, err := kdf.Key(passphrase, salt)
+	if err != nil {
+		return []byte{}, err
+	}
 
 	rawData, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
@@ -72,8 +123,12 @@ func Decrypt(data string, passphrase string, salt string) ([]byte, error) {
 	}
 
 	nonceSize := cipher.NonceSize()
+	if len(rawData) < nonceSize {
+		return []byte{}, fmt.Errorf("the encrypted data is too short")
+	}
+
 	nonce, text := rawData[:nonceSize], rawData[nonceSize:]
-	plaintext, err := cipher.Open(nil, nonce, text, nil)
+	plaintext, err := cipher.Open(nil, nonce, text, additionalData)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -81,31 +136,60 @@ func Decrypt(data string, passphrase string, salt string) ([]byte, error) {
 	return plaintext, nil
 }
 
-// RandomString generate a random string
+// Characters used to generate the random strings
+const (
+	Letters  = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	Digits   = "0123456789"
+	Specials = "~=+%^*/()[]{}!@#$?|"
+)
+
+// RandomString generate a random string with at least one char of each enabled class
 func RandomString(length int, letter bool, digit bool, special bool) string {
-	digits := "0123456789"
-	specials := "~=+%^*/()[]{}/!@#$?|"
-	letters := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-	chars := ""
-	randomString := make([]byte, length)
+	profile := PasswordProfile{Length: length, Letter: letter, Digit: digit, Special: special}
+	classes, _ := profile.Classes()
 
-	if letter {
-		chars = chars + letters
-	}
-	if digit {
-		chars = chars + digits
-	}
-	if special {
-		chars = chars + specials
+	return randomString(length, classes)
+}
+
+// RandomSalt generate a salt for the key derivation
+func RandomSalt() (string, error) {
+	salt := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return "", err
 	}
-	if !letter && !digit && !special {
-		chars = digits + letters
+
+	return base64.RawStdEncoding.EncodeToString(salt), nil
+}
+
+// randomString pick the chars with crypto/rand, the first chars are taken in
+// each class before the string is shuffled
+func randomString(length int, classes []string) string {
+	chars := strings.Join(classes, "")
+	randomString := make([]byte, length)
+
+	for i := range randomString {
+		if i < len(classes) {
+			randomString[i] = classes[i][randomInt(len(classes[i]))]
+		} else {
+			randomString[i] = chars[randomInt(len(chars))]
+		}
 	}
 
-	mrand.Seed(time.Now().UnixNano())
-	for i := 0; i < length; i++ {
-		randomString[i] = chars[mrand.Intn(len(chars))]
+	for i := len(randomString) - 1; i > 0; i-- {
+		j := randomInt(i + 1)
+		randomString[i], randomString[j] = randomString[j], randomString[i]
 	}
 
 	return string(randomString)
 }
+
+// randomInt return an unbiased random number in [0, max)
+func randomInt(max int) int {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		panic(fmt.Sprintf("unable to read random data: %s", err))
+	}
+
+	return int(n.Int64())
+}
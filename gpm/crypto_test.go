@@ -8,7 +8,7 @@ import (
 func TestEncrypt(t *testing.T) {
 	secret := []byte("secret data")
 
-	data, err := Encrypt(secret, "passphrase", "salt")
+	data, err := Encrypt(secret, "passphrase", "salt", DefaultKDF(), nil)
 	if err != nil {
 		t.Errorf("Encrypt mustn't return an error: %s", err)
 
@@ -21,8 +21,8 @@ func TestEncrypt(t *testing.T) {
 func TestDecrypt(t *testing.T) {
 	secret := "secret data"
 
-	dataEncrypted, _ := Encrypt([]byte(secret), "passphrase", "salt")
-	data, err := Decrypt(dataEncrypted, "passphrase", "salt")
+	dataEncrypted, _ := Encrypt([]byte(secret), "passphrase", "salt", DefaultKDF(), nil)
+	data, err := Decrypt(dataEncrypted, "passphrase", "salt", DefaultKDF(), nil)
 	if err != nil {
 		t.Errorf("Decrypt mustn't return an error: %s", err)
 	}
@@ -34,8 +34,8 @@ func TestDecrypt(t *testing.T) {
 func TestDecryptWithBadPassphrase(t *testing.T) {
 	secret := []byte("secret data")
 
-	dataEncrypted, _ := Encrypt(secret, "passphrase", "salt")
-	_, err := Decrypt(dataEncrypted, "bad", "salt")
+	dataEncrypted, _ := Encrypt(secret, "passphrase", "salt", DefaultKDF(), nil)
+	_, err := Decrypt(dataEncrypted, "bad", "salt", DefaultKDF(), nil)
 	if err == nil {
 		t.Error("Decrypt must return an error with bad passphrase")
 	}
@@ -44,13 +44,53 @@ func TestDecryptWithBadPassphrase(t *testing.T) {
 func TestDecryptWithBadSalt(t *testing.T) {
 	secret := []byte("secret data")
 
-	dataEncrypted, _ := Encrypt(secret, "passphrase", "salt")
-	_, err := Decrypt(dataEncrypted, "passphrase", "bad")
+	dataEncrypted, _ := Encrypt(secret, "passphrase", "salt", DefaultKDF(), nil)
+	_, err := Decrypt(dataEncrypted, "passphrase", "bad", DefaultKDF(), nil)
 	if err == nil {
 		t.Error("Decrypt must return an error with bad salt")
 	}
 }
 
+func TestDecryptWithBadAdditionalData(t *testing.T) {
+	secret := []byte("secret data")
+
+	dataEncrypted, _ := Encrypt(secret, "passphrase", "salt", DefaultKDF(), []byte("header"))
+	_, err := Decrypt(dataEncrypted, "passphrase", "salt", DefaultKDF(), []byte("bad"))
+	if err == nil {
+		t.Error("Decrypt must return an error with bad additional data")
+	}
+}
+
+func TestDecryptWithLegacyKDF(t *testing.T) {
+	secret := "secret data"
+
+	dataEncrypted, _ := Encrypt([]byte(secret), "passphrase", "salt", LegacyKDF(), nil)
+	data, err := Decrypt(dataEncrypted, "passphrase", "salt", LegacyKDF(), nil)
+	if err != nil {
+		t.Errorf("Decrypt mustn't return an error: %s", err)
+	}
+	if string(data) != secret {
+		t.Errorf("the encrypted secret is different of decrypted secret: %s", data)
+	}
+
+	_, err = Decrypt(dataEncrypted, "passphrase", "salt", DefaultKDF(), nil)
+	if err == nil {
+		t.Error("Decrypt must return an error with another KDF")
+	}
+}
+
+func TestEncryptWithBadKDF(t *testing.T) {
+	_, err := Encrypt([]byte("secret data"), "passphrase", "salt", KDF{Name: "bad"}, nil)
+	if err == nil {
+		t.Error("Encrypt must return an error with an unknown KDF")
+	}
+
+	_, err = Encrypt([]byte("secret data"), "passphrase", "salt", KDF{Name: KDFArgon2id}, nil)
+	if err == nil {
+		t.Error("Encrypt must return an error with argon2id without parameters")
+	}
+}
+
 func TestRandomStringLength(t *testing.T) {
 	password := RandomString(64, false, false, false)
 	if len(password) != 64 {
@@ -89,3 +129,32 @@ func TestRandomStringOnlySpecial(t *testing.T) {
 		t.Errorf("the string must contain only alphabetic characters: %s", password)
 	}
 }
+
+func TestRandomStringWithAllClasses(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		password := RandomString(4, true, true, true)
+		if !regexp.MustCompile(`[a-zA-Z]`).MatchString(password) ||
+			!regexp.MustCompile(`[0-9]`).MatchString(password) ||
+			!regexp.MustCompile(`[\~\=\+\%\^\*\/\(\)\[\]\{\}\!\@\#\$\?\|]`).MatchString(password) {
+			t.Errorf("the string must contain a char of each class: %s", password)
+		}
+	}
+}
+
+func TestRandomStringIsUnique(t *testing.T) {
+	if RandomString(16, true, true, false) == RandomString(16, true, true, false) {
+		t.Error("two random strings mustn't be identical")
+	}
+}
+
+func TestRandomSalt(t *testing.T) {
+	salt, err := RandomSalt()
+	if err != nil {
+		t.Errorf("RandomSalt mustn't return an error: %s", err)
+	}
+
+	otherSalt, _ := RandomSalt()
+	if salt == "" || salt == otherSalt {
+		t.Errorf("two salts mustn't be identical: %s", salt)
+	}
+}
@@ -17,6 +17,7 @@ package gpm
 import (
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pquerna/otp/totp"
@@ -24,16 +25,24 @@ import (
 
 // Entry struct have the password informations
 type Entry struct {
-	Name       string
-	ID         string
-	URI        string
-	User       string
-	Password   string
-	OTP        string
-	Group      string
-	Comment    string
-	Create     int64
-	LastUpdate int64
+	Name           string
+	ID             string
+	URI            string
+	User           string
+	Password       string
+	OTP            string
+	Group          string
+	Comment        string
+	Tags           []string      `json:",omitempty"`
+	Fields         []CustomField `json:",omitempty"`
+	Attachments    []Attachment  `json:",omitempty"`
+	Expire         int64         `json:",omitempty"`
+	RotateDays     int           `json:",omitempty"`
+	PasswordChange int64         `json:",omitempty"`
+	Create         int64
+	LastUpdate     int64
+	Deleted        int64   `json:",omitempty"`
+	History        []Entry `json:",omitempty"`
 }
 
 // Verify if the item have'nt error
@@ -51,6 +60,17 @@ func (e *Entry) Verify() error {
 		return fmt.Errorf("the uri isn't a valid uri")
 	}
 
+	if e.RotateDays < 0 {
+		return fmt.Errorf("the rotation period can't be negative")
+	}
+
+	for _, field := range e.Fields {
+		err := field.Verify()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -59,6 +79,53 @@ func (e *Entry) GenerateID() {
 	e.ID = fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
+// Field return the custom field with this name
+func (e *Entry) Field(name string) (CustomField, error) {
+	for _, field := range e.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, nil
+		}
+	}
+
+	return CustomField{}, fmt.Errorf("the field %s doesn't exist", name)
+}
+
+// Value return the value of a standard field or of a custom field, the
+// otp field is the current OTP code
+func (e *Entry) Value(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "name":
+		return e.Name, nil
+	case "id":
+		return e.ID, nil
+	case "uri":
+		return e.URI, nil
+	case "user":
+		return e.User, nil
+	case "password":
+		return e.Password, nil
+	case "group":
+		return e.Group, nil
+	case "comment":
+		return e.Comment, nil
+	case "tags":
+		return strings.Join(e.Tags, ","), nil
+	case "otp":
+		if e.OTP == "" {
+			return "", fmt.Errorf("the entry %s hasn't an OTP key", e.Name)
+		}
+		code, _, err := e.OTPCode()
+		return code, err
+	}
+
+	field, err := e.Field(name)
+	if err != nil {
+		return "", err
+	}
+
+	return field.Value, nil
+}
+
 // OTPCode generate an OTP Code
 func (e *Entry) OTPCode() (string, int64, error) {
 	code, err := totp.GenerateCode(e.OTP, time.Now())
@@ -63,3 +63,39 @@ func TestGenerateOTPCode(t *testing.T) {
 		t.Errorf("time must be between 0 and 30: %d", time)
 	}
 }
+
+func TestEntryValue(t *testing.T) {
+	entry := Entry{
+		Name:     "test",
+		User:     "me",
+		Password: "secret",
+		Tags:     []string{"work", "aws"},
+		Fields:   []CustomField{{Name: "PIN", Type: FieldSecret, Value: "1234"}},
+	}
+
+	for name, value := range map[string]string{"user": "me", "Password": "secret", "tags": "work,aws", "pin": "1234"} {
+		result, err := entry.Value(name)
+		if err != nil {
+			t.Errorf("the field %s mustn't return an error: %s", name, err)
+		}
+		if result != value {
+			t.Errorf("the field %s must be %s: %s", name, value, result)
+		}
+	}
+
+	_, err := entry.Value("otp")
+	if err == nil {
+		t.Error("an entry without OTP key must return an error")
+	}
+
+	_, err = entry.Value("unknown")
+	if err == nil {
+		t.Error("an unknown field must return an error")
+	}
+
+	entry.OTP = "JBSWY3DPEHPK3PXP"
+	code, err := entry.Value("otp")
+	if err != nil || len(code) != 6 {
+		t.Errorf("the otp field must be an OTP code: %s %v", code, err)
+	}
+}
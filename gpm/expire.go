@@ -0,0 +1,84 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExpireDateFormat is the format of the expiration dates
+const ExpireDateFormat = "2006-01-02"
+
+// ParseExpire return the timestamp of an expiration date, 0 for an empty date
+func ParseExpire(date string) (int64, error) {
+	if date == "" {
+		return 0, nil
+	}
+
+	expire, err := time.ParseInLocation(ExpireDateFormat, date, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("the expiration date must have the format YYYY-MM-DD")
+	}
+
+	return expire.Unix(), nil
+}
+
+// ExpireDate return the date when the password must be changed, with the
+// expiration date or the rotation policy, 0 if the password doesn't expire
+func (e *Entry) ExpireDate() int64 {
+	expire := e.Expire
+
+	if e.RotateDays > 0 {
+		change := e.PasswordChange
+		if change == 0 {
+			change = e.Create
+		}
+
+		rotate := time.Unix(change, 0).AddDate(0, 0, e.RotateDays).Unix()
+		if expire == 0 || rotate < expire {
+			expire = rotate
+		}
+	}
+
+	return expire
+}
+
+// Expired return true if the password expires before the date
+func (e *Entry) Expired(date time.Time) bool {
+	expire := e.ExpireDate()
+
+	return expire > 0 && expire <= date.Unix()
+}
+
+// ExpiringEntries return the entries expired or expiring in the next days,
+// sorted by expiration date
+func (w *Wallet) ExpiringEntries(days int) []Entry {
+	var entries []Entry
+
+	limit := time.Now().AddDate(0, 0, days)
+	for _, entry := range w.Entries {
+		if entry.Expired(limit) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ExpireDate() < entries[j].ExpireDate()
+	})
+
+	return entries
+}
@@ -0,0 +1,77 @@
+package gpm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpire(t *testing.T) {
+	expire, err := ParseExpire("")
+	if err != nil || expire != 0 {
+		t.Errorf("an empty date must return 0: %d %v", expire, err)
+	}
+
+	_, err = ParseExpire("31/01/2030")
+	if err == nil {
+		t.Error("a bad date must return an error")
+	}
+
+	expire, err = ParseExpire("2030-01-31")
+	if err != nil || time.Unix(expire, 0).Format(ExpireDateFormat) != "2030-01-31" {
+		t.Errorf("a good date mustn't return an error: %d %v", expire, err)
+	}
+}
+
+func TestExpireDate(t *testing.T) {
+	now := time.Now()
+	entry := Entry{PasswordChange: now.AddDate(0, 0, -20).Unix()}
+	if entry.ExpireDate() != 0 || entry.Expired(now) {
+		t.Error("an entry without expiration mustn't expire")
+	}
+
+	entry.RotateDays = 30
+	if entry.Expired(now) || !entry.Expired(now.AddDate(0, 0, 11)) {
+		t.Errorf("the password must expire 30 days after the change: %d", entry.ExpireDate())
+	}
+
+	entry.Expire = now.AddDate(0, 0, -1).Unix()
+	if !entry.Expired(now) {
+		t.Error("the earliest expiration date must be used")
+	}
+}
+
+func TestExpiringEntries(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.MaxHistory = 10
+
+	entry := wallet.SearchEntryByID("1")
+	entry.Expire = time.Now().AddDate(0, 0, 10).Unix()
+	wallet.UpdateEntry(entry)
+
+	entry = wallet.SearchEntryByID("2")
+	entry.Expire = time.Now().AddDate(0, 0, -1).Unix()
+	wallet.UpdateEntry(entry)
+
+	entry = wallet.SearchEntryByID("3")
+	entry.RotateDays = 90
+	wallet.UpdateEntry(entry)
+
+	entries := wallet.ExpiringEntries(14)
+	if len(entries) != 2 {
+		t.Fatalf("must have 2 expiring entries: %d", len(entries))
+	}
+	if entries[0].ID != "2" || entries[1].ID != "1" {
+		t.Errorf("the entries must be sorted by expiration date: %s %s", entries[0].ID, entries[1].ID)
+	}
+
+	entry = wallet.SearchEntryByID("3")
+	wallet.UpdateEntry(Entry{ID: "3", Name: "Entry 3", PasswordChange: 1})
+	if wallet.SearchEntryByID("3").PasswordChange != entry.PasswordChange {
+		t.Error("the password change date mustn't be updated without a new password")
+	}
+
+	wallet.UpdateEntry(Entry{ID: "3", Name: "Entry 3", Password: "new password"})
+	if wallet.SearchEntryByID("3").PasswordChange < entry.PasswordChange {
+		t.Error("the password change date must be updated with a new password")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom field types
+const (
+	FieldText   = "text"
+	FieldSecret = "secret"
+	FieldURL    = "url"
+	FieldEmail  = "email"
+	FieldNumber = "number"
+	FieldDate   = "date"
+)
+
+// FieldDateFormat is the format of the date fields
+const FieldDateFormat = "2006-01-02"
+
+// FieldTypes is the list of the custom field types
+var FieldTypes = []string{FieldText, FieldSecret, FieldURL, FieldEmail, FieldNumber, FieldDate}
+
+// CustomField is an additional information of an entry
+type CustomField struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// Verify if the field have'nt error
+func (f *CustomField) Verify() error {
+	if f.Name == "" {
+		return fmt.Errorf("you must define a name for the field")
+	}
+
+	switch f.Type {
+	case FieldText, FieldSecret:
+	case FieldURL:
+		uri, err := url.Parse(f.Value)
+		if err != nil || uri.Host == "" {
+			return fmt.Errorf("the field %s isn't a valid url", f.Name)
+		}
+	case FieldEmail:
+		address, err := mail.ParseAddress(f.Value)
+		if err != nil || address.Address != f.Value {
+			return fmt.Errorf("the field %s isn't a valid email", f.Name)
+		}
+	case FieldNumber:
+		_, err := strconv.ParseFloat(f.Value, 64)
+		if err != nil {
+			return fmt.Errorf("the field %s isn't a valid number", f.Name)
+		}
+	case FieldDate:
+		_, err := time.Parse(FieldDateFormat, f.Value)
+		if err != nil {
+			return fmt.Errorf("the field %s isn't a valid date (%s)", f.Name, FieldDateFormat)
+		}
+	default:
+		return fmt.Errorf("the field type %s is unknown, it must be one of: %s", f.Type, strings.Join(FieldTypes, ", "))
+	}
+
+	return nil
+}
+
+// Secret return true if the field value must be hidden
+func (f *CustomField) Secret() bool {
+	return f.Type == FieldSecret
+}
+
+// Display return the value to print, the secret values are masked
+func (f *CustomField) Display() string {
+	if f.Secret() {
+		return strings.Repeat("*", 8)
+	}
+
+	return f.Value
+}
+
+// ParseField create a field from a string with the format name[:type]=value,
+// the type is text by default
+func ParseField(spec string) (CustomField, error) {
+	var field CustomField
+
+	index := strings.Index(spec, "=")
+	if index < 0 {
+		return field, fmt.Errorf("the field %s must have the format name[:type]=value", spec)
+	}
+
+	field.Name = spec[:index]
+	field.Value = spec[index+1:]
+	field.Type = FieldText
+	if index := strings.LastIndex(field.Name, ":"); index >= 0 {
+		field.Type = field.Name[index+1:]
+		field.Name = field.Name[:index]
+	}
+
+	return field, field.Verify()
+}
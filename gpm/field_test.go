@@ -0,0 +1,74 @@
+package gpm
+
+import (
+	"testing"
+)
+
+func TestVerifyCustomFields(t *testing.T) {
+	goodFields := []CustomField{
+		{Name: "PIN", Type: FieldSecret, Value: "1234"},
+		{Name: "Question", Type: FieldText, Value: "first pet"},
+		{Name: "Console", Type: FieldURL, Value: "https://console.example.com"},
+		{Name: "Recovery", Type: FieldEmail, Value: "me@example.com"},
+		{Name: "Account", Type: FieldNumber, Value: "42.5"},
+		{Name: "Expire", Type: FieldDate, Value: "2030-01-31"},
+	}
+	for _, field := range goodFields {
+		err := field.Verify()
+		if err != nil {
+			t.Errorf("a good %s field mustn't return an error: %s", field.Type, err)
+		}
+	}
+
+	badFields := []CustomField{
+		{Type: FieldText, Value: "no name"},
+		{Name: "Bad", Type: "bad", Value: "value"},
+		{Name: "Console", Type: FieldURL, Value: "console"},
+		{Name: "Recovery", Type: FieldEmail, Value: "me@"},
+		{Name: "Account", Type: FieldNumber, Value: "forty-two"},
+		{Name: "Expire", Type: FieldDate, Value: "31/01/2030"},
+	}
+	for _, field := range badFields {
+		err := field.Verify()
+		if err == nil {
+			t.Errorf("a bad %s field must return an error: %s", field.Type, field.Value)
+		}
+	}
+}
+
+func TestDisplaySecretField(t *testing.T) {
+	field := CustomField{Name: "PIN", Type: FieldSecret, Value: "1234"}
+	if field.Display() == field.Value {
+		t.Error("a secret field must be masked")
+	}
+
+	field.Type = FieldText
+	if field.Display() != field.Value {
+		t.Errorf("a text field mustn't be masked: %s", field.Display())
+	}
+}
+
+func TestParseField(t *testing.T) {
+	field, err := ParseField("PIN:secret=12=34")
+	if err != nil {
+		t.Fatalf("a good field mustn't return an error: %s", err)
+	}
+	if field.Name != "PIN" || field.Type != FieldSecret || field.Value != "12=34" {
+		t.Errorf("the field must be parsed: %+v", field)
+	}
+
+	field, err = ParseField("Question=first pet")
+	if err != nil {
+		t.Fatalf("a field without type mustn't return an error: %s", err)
+	}
+	if field.Type != FieldText {
+		t.Errorf("the default type must be text: %s", field.Type)
+	}
+
+	for _, spec := range []string{"PIN", "=value", "Account:number=forty-two", "PIN:bad=1234"} {
+		_, err = ParseField(spec)
+		if err == nil {
+			t.Errorf("a bad field must return an error: %s", spec)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitRemoteName is the name of the remote used to sync the wallets
+const GitRemoteName = "origin"
+
+// Files ignored in the git repository of the wallets
+var gitIgnore = []string{"*.bak", "*.lock", ".*.gpm-*"}
+
+// GitRepository is the wallet directory versioned with git
+type GitRepository struct {
+	Path   string
+	Remote string
+}
+
+// Init create the git repository if it doesn't exist and set the remote
+func (g *GitRepository) Init() error {
+	_, err := os.Stat(filepath.Join(g.Path, ".git"))
+	if os.IsNotExist(err) {
+		_, err = g.run("init")
+	}
+	if err != nil {
+		return err
+	}
+
+	ignorePath := filepath.Join(g.Path, ".gitignore")
+	_, err = os.Stat(ignorePath)
+	if os.IsNotExist(err) {
+		err = ioutil.WriteFile(ignorePath, []byte(strings.Join(gitIgnore, "\n")+"\n"), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if g.Remote == "" {
+		return nil
+	}
+
+	url, err := g.run("remote", "get-url", GitRemoteName)
+	if err != nil {
+		_, err = g.run("remote", "add", GitRemoteName, g.Remote)
+	} else if url != g.Remote {
+		_, err = g.run("remote", "set-url", GitRemoteName, g.Remote)
+	}
+
+	return err
+}
+
+// Commit the file with the message if it has been modified
+func (g *GitRepository) Commit(file string, message string) error {
+	args := []string{"add", "--"}
+	for _, path := range []string{file, ".gitignore"} {
+		if _, err := os.Stat(filepath.Join(g.Path, path)); err == nil {
+			args = append(args, path)
+		}
+	}
+
+	_, err := g.run(args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.run("diff", "--cached", "--quiet")
+	if err == nil && !g.merging() {
+		return nil
+	}
+
+	return g.commit(message)
+}
+
+// commit the staged files
+func (g *GitRepository) commit(message string) error {
+	_, err := g.runWithIdentity("commit", "--no-verify", "-m", message)
+	return err
+}
+
+// runWithIdentity run a git command creating a commit, a default identity is
+// used if git hasn't one
+func (g *GitRepository) runWithIdentity(args ...string) (string, error) {
+	email, _ := g.run("config", "user.email")
+	if email == "" {
+		args = append([]string{"-c", "user.name=gpm", "-c", "user.email=gpm@localhost"}, args...)
+	}
+
+	return g.run(args...)
+}
+
+// merging return true if a merge is in progress
+func (g *GitRepository) merging() bool {
+	_, err := g.run("rev-parse", "--verify", "--quiet", "MERGE_HEAD")
+	return err == nil
+}
+
+// run a git command in the repository and return the output
+func (g *GitRepository) run(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", g.Path}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		command := args[0]
+		for i := 0; i+2 < len(args) && args[i] == "-c"; i += 2 {
+			command = args[i+2]
+		}
+		return "", fmt.Errorf("git %s: %s", command, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// show return the content of a file in a commit, nil if it doesn't exist
+func (g *GitRepository) show(ref string, file string) []byte {
+	content, err := g.run("show", fmt.Sprintf("%s:%s", ref, file))
+	if err != nil {
+		return nil
+	}
+
+	return []byte(content)
+}
+
+// Sync fetch the remote, merge the concurrent changes of the wallet and
+// push the result
+func (w *Wallet) Sync() (MergeResult, error) {
+	var result MergeResult
+
+	if w.Git == nil {
+		return result, fmt.Errorf("the wallet directory isn't a git repository")
+	}
+	if w.ReadOnly {
+		return result, fmt.Errorf("the wallet is open in read-only mode")
+	}
+	if w.Git.Remote == "" {
+		return result, fmt.Errorf("you must define a git remote")
+	}
+
+	err := w.Lock()
+	if err != nil {
+		return result, err
+	}
+
+	file := filepath.Base(w.Path)
+	err = w.Git.Commit(file, fmt.Sprintf("Update the wallet %s", w.Name))
+	if err != nil {
+		return result, err
+	}
+
+	branch, err := w.Git.run("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return result, err
+	}
+	remoteRef := fmt.Sprintf("%s/%s", GitRemoteName, branch)
+
+	_, err = w.Git.run("fetch", GitRemoteName)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = w.Git.run("rev-parse", "--verify", "--quiet", remoteRef)
+	if err == nil {
+		result, err = w.pull(file, remoteRef)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	_, err = w.Git.run("push", "--set-upstream", GitRemoteName, branch)
+	return result, err
+}
+
+// pull merge the remote branch, the wallet file is merged by entries
+func (w *Wallet) pull(file string, remoteRef string) (MergeResult, error) {
+	var base, theirs WalletData
+
+	if content := w.Git.show(remoteRef, file); content != nil {
+		_, walletData, err := w.decode(content)
+		if err != nil {
+			return MergeResult{}, fmt.Errorf("the remote wallet can't be decrypted: %s", err)
+		}
+		theirs = walletData
+	}
+
+	_, err := w.Git.run("merge-base", "--is-ancestor", remoteRef, "HEAD")
+	if err == nil {
+		return MergeResult{}, nil
+	}
+
+	mergeBase, err := w.Git.run("merge-base", "HEAD", remoteRef)
+	if err == nil {
+		if content := w.Git.show(mergeBase, file); content != nil {
+			_, base, _ = w.decode(content)
+		}
+	}
+	result := mergeWalletData(base, WalletData{Entries: w.Entries, Trash: w.Trash}, theirs, w.Resolve)
+
+	_, err = w.Git.run("merge-base", "--is-ancestor", "HEAD", remoteRef)
+	if err == nil {
+		_, err = w.Git.runWithIdentity("merge", "--ff-only", remoteRef)
+		if err != nil {
+			return result, err
+		}
+		return result, w.read()
+	}
+
+	_, err = w.Git.runWithIdentity("merge", "--no-ff", "--no-commit", "--allow-unrelated-histories", remoteRef)
+	if err != nil && !w.Git.merging() {
+		w.Git.run("merge", "--abort")
+		return result, err
+	}
+	conflicts, err := w.Git.run("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return result, err
+	}
+	for _, conflict := range strings.Fields(conflicts) {
+		if conflict != file {
+			w.Git.run("merge", "--abort")
+			return result, fmt.Errorf("the file %s has conflicts, sync it before", conflict)
+		}
+	}
+	w.Git.run("checkout", "--ours", "--", file)
+
+	w.Entries = result.Entries
+	w.Trash = result.Trash
+	err = w.write()
+	if err != nil {
+		w.Git.run("merge", "--abort")
+		return result, err
+	}
+
+	err = w.Git.Commit(file, fmt.Sprintf("Merge the wallet %s: %s", w.Name, changesMessage(result)))
+	return result, err
+}
+
+// commitMessage describe the changes between two versions of the entries,
+// only the IDs are written to never leak a secret
+func commitMessage(name string, before []Entry, after []Entry) string {
+	result := MergeEntries(before, before, after, nil)
+	if len(result.Added)+len(result.Updated)+len(result.Deleted) == 0 {
+		return fmt.Sprintf("Update the wallet %s", name)
+	}
+
+	return fmt.Sprintf("Update the wallet %s: %s", name, changesMessage(result))
+}
+
+// changesMessage describe the entries added, updated and deleted by IDs
+func changesMessage(result MergeResult) string {
+	var changes []string
+
+	for _, change := range []struct {
+		action  string
+		entries []Entry
+	}{
+		{"added", result.Added},
+		{"updated", result.Updated},
+		{"deleted", result.Deleted},
+	} {
+		if len(change.entries) == 0 {
+			continue
+		}
+
+		var ids []string
+		for _, entry := range change.entries {
+			ids = append(ids, entry.ID)
+		}
+		changes = append(changes, fmt.Sprintf("%d %s (%s)", len(ids), change.action, strings.Join(ids, ", ")))
+	}
+
+	if len(changes) == 0 {
+		return "no change"
+	}
+
+	return strings.Join(changes, ", ")
+}
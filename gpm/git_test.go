@@ -0,0 +1,136 @@
+package gpm
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func generateGitWallet(dir string, remote string) Wallet {
+	os.MkdirAll(dir, 0700)
+	repository := GitRepository{Path: dir, Remote: remote}
+	repository.Init()
+
+	return Wallet{
+		Name:       "test",
+		Path:       filepath.Join(dir, "test.gpm"),
+		Passphrase: "secret",
+		Git:        &repository,
+	}
+}
+
+func TestSaveCommitWallet(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("git isn't available: %s", err)
+	}
+
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := generateGitWallet(dir, "")
+	wallet.AddEntry(Entry{ID: "1", Name: "Secret Entry", Password: "secret password"})
+	err := wallet.Save()
+	if err != nil {
+		t.Fatalf("save wallet mustn't return an error: %s", err)
+	}
+
+	log, err := wallet.Git.run("log", "--format=%B")
+	if err != nil {
+		t.Fatalf("the wallet must be committed: %s", err)
+	}
+	if !strings.Contains(log, "1 added (1)") {
+		t.Errorf("the commit message must contain the added entry: %s", log)
+	}
+	if strings.Contains(log, "Secret") || strings.Contains(log, "secret") {
+		t.Errorf("the commit message mustn't contain the entry data: %s", log)
+	}
+
+	status, _ := wallet.Git.run("status", "--porcelain")
+	if status != "" {
+		t.Errorf("the backups and the lock must be ignored: %s", status)
+	}
+}
+
+func TestSyncWallets(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "remote.git")
+	err := exec.Command("git", "init", "--bare", remote).Run()
+	if err != nil {
+		t.Skipf("git isn't available: %s", err)
+	}
+
+	laptop := generateGitWallet(filepath.Join(dir, "laptop"), remote)
+	for _, entry := range generateWalletWithEntries().Entries {
+		laptop.AddEntry(entry)
+	}
+	laptop.Save()
+	_, err = laptop.Sync()
+	if err != nil {
+		t.Fatalf("sync wallet mustn't return an error: %s", err)
+	}
+
+	desktop := generateGitWallet(filepath.Join(dir, "desktop"), remote)
+	desktop.Load()
+	_, err = desktop.Sync()
+	if err != nil {
+		t.Fatalf("sync a new wallet mustn't return an error: %s", err)
+	}
+	if len(desktop.Entries) != 10 {
+		t.Fatalf("the new wallet must have 10 entries: %d", len(desktop.Entries))
+	}
+
+	desktop.AddEntry(Entry{ID: "10", Name: "Entry 10"})
+	desktop.Save()
+	desktop.Sync()
+	laptop.UpdateEntry(Entry{ID: "5", Name: "Entry 5 updated"})
+	laptop.Save()
+
+	result, err := laptop.Sync()
+	if err != nil {
+		t.Fatalf("sync diverged wallets mustn't return an error: %s", err)
+	}
+	if len(result.Added) != 1 || len(result.Conflicts) != 0 {
+		t.Errorf("must have 1 added entry and 0 conflict: %d %d", len(result.Added), len(result.Conflicts))
+	}
+	if len(laptop.Entries) != 11 || laptop.SearchEntryByID("5").Name != "Entry 5 updated" {
+		t.Errorf("the changes of the both wallets must be kept: %d", len(laptop.Entries))
+	}
+
+	desktop.Sync()
+	if len(desktop.Entries) != 11 || desktop.SearchEntryByID("5").Name != "Entry 5 updated" {
+		t.Errorf("the merged wallet must be pulled: %d", len(desktop.Entries))
+	}
+}
+
+func TestSaveWithFailedCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("git isn't available: %s", err)
+	}
+
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	defer os.RemoveAll(dir)
+
+	wallet := Wallet{
+		Name:       "test",
+		Path:       filepath.Join(dir, "test.gpm"),
+		Passphrase: "secret",
+		Git:        &GitRepository{Path: dir},
+	}
+	wallet.AddEntry(Entry{ID: "1", Name: "Entry 1"})
+	err := wallet.Save()
+	if err == nil || !strings.Contains(err.Error(), "has been saved") {
+		t.Errorf("a failed commit must return an error after the save: %v", err)
+	}
+	wallet.Unlock()
+
+	loadWallet := Wallet{Path: wallet.Path, Passphrase: "secret", ReadOnly: true}
+	loadWallet.Load()
+	if len(loadWallet.Entries) != 1 {
+		t.Errorf("the wallet must be saved: %d", len(loadWallet.Entries))
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupSeparator is the separator between the parent and the child groups
+const GroupSeparator = "/"
+
+// CleanGroup return the group path without the spaces and the empty groups
+func CleanGroup(group string) string {
+	var names []string
+
+	for _, name := range strings.Split(group, GroupSeparator) {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, GroupSeparator)
+}
+
+// InGroup return true if the group is the parent group or one of its descendants
+func InGroup(group string, parent string) bool {
+	names, parents := strings.Split(group, GroupSeparator), strings.Split(parent, GroupSeparator)
+	if len(parents) > len(names) {
+		return false
+	}
+
+	for i := range parents {
+		if !strings.EqualFold(names[i], parents[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GroupDepth return the number of parents of a group
+func GroupDepth(group string) int {
+	return strings.Count(group, GroupSeparator)
+}
+
+// GroupTree return all the groups with their parents sorted as a tree
+func (w *Wallet) GroupTree() []string {
+	var groups []string
+
+	for _, group := range w.Groups() {
+		names := strings.Split(group, GroupSeparator)
+		for i := range names {
+			path := strings.Join(names[:i+1], GroupSeparator)
+			found := false
+			for _, g := range groups {
+				if strings.EqualFold(g, path) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, path)
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groupSortKey(groups[i]) < groupSortKey(groups[j])
+	})
+
+	return groups
+}
+
+// groupSortKey return the key to sort the groups name by name, the separator
+// is replaced by a null byte to place a parent before its siblings like
+// work-old or work.old
+func groupSortKey(group string) string {
+	return strings.Replace(strings.ToLower(group), GroupSeparator, "\x00", -1)
+}
+
+// RenameGroup rename or move a group with all its descendants, return the
+// number of updated entries
+func (w *Wallet) RenameGroup(oldGroup string, newGroup string) (int, error) {
+	oldGroup, newGroup = CleanGroup(oldGroup), CleanGroup(newGroup)
+	if oldGroup == "" || newGroup == "" {
+		return 0, fmt.Errorf("the group name can't be empty")
+	}
+
+	count := 0
+	for _, entry := range w.Entries {
+		if !InGroup(entry.Group, oldGroup) {
+			continue
+		}
+
+		names := strings.Split(entry.Group, GroupSeparator)[GroupDepth(oldGroup)+1:]
+		entry.Group = strings.Join(append([]string{newGroup}, names...), GroupSeparator)
+		err := w.UpdateEntry(entry)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("the group %s doesn't exist", oldGroup)
+	}
+
+	return count, nil
+}
+
+// MergeGroups move the entries of the groups and their descendants in the
+// target group, return the number of updated entries
+func (w *Wallet) MergeGroups(groups []string, target string) (int, error) {
+	count := 0
+	target = CleanGroup(target)
+
+	for _, group := range groups {
+		if InGroup(target, group) {
+			return count, fmt.Errorf("the group %s can't be merged in itself", group)
+		}
+	}
+
+	for _, group := range groups {
+		n, err := w.RenameGroup(group, target)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// DeleteGroup remove a group and its descendants, the entries are ungrouped
+// or moved to the trash, return the number of entries
+func (w *Wallet) DeleteGroup(group string, trash bool) (int, error) {
+	var ids []string
+
+	group = CleanGroup(group)
+	if group == "" {
+		return 0, fmt.Errorf("the group name can't be empty")
+	}
+
+	for _, entry := range w.Entries {
+		if InGroup(entry.Group, group) {
+			ids = append(ids, entry.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("the group %s doesn't exist", group)
+	}
+
+	for _, id := range ids {
+		var err error
+		if trash {
+			err = w.DeleteEntry(id)
+		} else {
+			entry := w.SearchEntryByID(id)
+			entry.Group = ""
+			err = w.UpdateEntry(entry)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
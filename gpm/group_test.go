@@ -0,0 +1,147 @@
+package gpm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func generateWalletWithGroups() Wallet {
+	var wallet Wallet
+
+	for i, group := range []string{"work/aws/prod", "work/aws/dev", "Work/github", "personal", ""} {
+		wallet.AddEntry(Entry{ID: string(rune('a' + i)), Name: fmt.Sprintf("Entry %d", i), Group: group})
+	}
+
+	return wallet
+}
+
+func TestCleanGroup(t *testing.T) {
+	group := CleanGroup(" work / aws//prod/")
+	if group != "work/aws/prod" {
+		t.Errorf("the group must be cleaned: %s", group)
+	}
+}
+
+func TestSearchEntriesInSubgroups(t *testing.T) {
+	wallet := generateWalletWithGroups()
+
+	entries := len(wallet.SearchEntry("", "work", false))
+	if entries != 3 {
+		t.Errorf("a search with a parent group must return the descendants: %d", entries)
+	}
+
+	entries = len(wallet.SearchEntry("", "work/aws", false))
+	if entries != 2 {
+		t.Errorf("a search with a sub group must return 2 entries: %d", entries)
+	}
+
+	entries = len(wallet.SearchEntry("", "wor", false))
+	if entries != 0 {
+		t.Errorf("a search with a part of group name must return 0 entry: %d", entries)
+	}
+}
+
+func TestGroupTree(t *testing.T) {
+	wallet := generateWalletWithGroups()
+
+	tree := strings.Join(wallet.GroupTree(), ",")
+	if tree != "personal,work,work/aws,work/aws/dev,work/aws/prod,Work/github" {
+		t.Errorf("the tree must contain all the groups and their parents: %s", tree)
+	}
+}
+
+func TestGroupTreeWithSiblings(t *testing.T) {
+	var wallet Wallet
+
+	for i, group := range []string{"work-old", "work/aws", "Personal Finance", "Personal/bank", "work.new"} {
+		wallet.AddEntry(Entry{ID: fmt.Sprintf("%d", i), Name: group, Group: group})
+	}
+
+	tree := strings.Join(wallet.GroupTree(), ",")
+	if tree != "Personal,Personal/bank,Personal Finance,work,work/aws,work-old,work.new" {
+		t.Errorf("the sub groups must be placed before the siblings of their parent: %s", tree)
+	}
+}
+
+func TestRenameGroup(t *testing.T) {
+	wallet := generateWalletWithGroups()
+
+	_, err := wallet.RenameGroup("bad", "good")
+	if err == nil {
+		t.Error("rename an unknown group must return an error")
+	}
+
+	count, err := wallet.RenameGroup("work/aws", "cloud/amazon")
+	if err != nil {
+		t.Errorf("rename a group mustn't return an error: %s", err)
+	}
+	if count != 2 {
+		t.Errorf("rename a group must update 2 entries: %d", count)
+	}
+	if wallet.SearchEntryByID("a").Group != "cloud/amazon/prod" {
+		t.Errorf("the sub groups must be moved: %s", wallet.SearchEntryByID("a").Group)
+	}
+	if wallet.SearchEntryByID("c").Group != "Work/github" {
+		t.Errorf("the other groups mustn't be moved: %s", wallet.SearchEntryByID("c").Group)
+	}
+}
+
+func TestRenameGroupWithCaseFolding(t *testing.T) {
+	var wallet Wallet
+
+	wallet.AddEntry(Entry{ID: "a", Name: "Entry", Group: "\u212Aeys/ssh"})
+	_, err := wallet.RenameGroup("keys", "secrets")
+	if err != nil {
+		t.Errorf("rename a group with another case mustn't return an error: %s", err)
+	}
+	if wallet.SearchEntryByID("a").Group != "secrets/ssh" {
+		t.Errorf("the group must be renamed by names: %s", wallet.SearchEntryByID("a").Group)
+	}
+}
+
+func TestMergeGroups(t *testing.T) {
+	wallet := generateWalletWithGroups()
+
+	_, err := wallet.MergeGroups([]string{"work"}, "work/aws")
+	if err == nil {
+		t.Error("merge a group in its sub group must return an error")
+	}
+
+	count, err := wallet.MergeGroups([]string{"work/aws", "personal"}, "archive")
+	if err != nil {
+		t.Errorf("merge groups mustn't return an error: %s", err)
+	}
+	if count != 3 {
+		t.Errorf("merge groups must update 3 entries: %d", count)
+	}
+	if wallet.SearchEntryByID("b").Group != "archive/dev" || wallet.SearchEntryByID("d").Group != "archive" {
+		t.Errorf("the entries must be moved in the target group: %s %s",
+			wallet.SearchEntryByID("b").Group, wallet.SearchEntryByID("d").Group)
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	wallet := generateWalletWithGroups()
+
+	_, err := wallet.DeleteGroup("bad", false)
+	if err == nil {
+		t.Error("delete an unknown group must return an error")
+	}
+
+	count, err := wallet.DeleteGroup("work/aws", false)
+	if err != nil || count != 2 {
+		t.Errorf("delete a group must ungroup 2 entries: %d %v", count, err)
+	}
+	if wallet.SearchEntryByID("a").Group != "" {
+		t.Errorf("the entries must be ungrouped: %s", wallet.SearchEntryByID("a").Group)
+	}
+
+	count, err = wallet.DeleteGroup("work", true)
+	if err != nil || count != 1 {
+		t.Errorf("delete a group must move 1 entry to the trash: %d %v", count, err)
+	}
+	if len(wallet.Entries) != 4 || len(wallet.Trash) != 1 {
+		t.Errorf("the entry must be moved to the trash: %d %d", len(wallet.Entries), len(wallet.Trash))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// errLocked is returned by lockFile when another process has the lock
+var errLocked = errors.New("the file is locked")
+
+// LockError is returned when the wallet is open in another process, the
+// PID is 0 if the process hasn't written it yet
+type LockError struct {
+	PID  int
+	Host string
+}
+
+// Error return the error message
+func (e *LockError) Error() string {
+	hostname, _ := os.Hostname()
+	switch {
+	case e.PID == 0:
+		return "wallet is open in another process"
+	case e.Host != hostname:
+		return fmt.Sprintf("wallet is open in another process (pid %d on %s)", e.PID, e.Host)
+	}
+
+	return fmt.Sprintf("wallet is open in another process (pid %d)", e.PID)
+}
+
+// LockPath return the path of the wallet lock file
+func (w *Wallet) LockPath() string {
+	return w.Path + ".lock"
+}
+
+// Lock the wallet for the current process with an advisory lock on the lock
+// file, released by the system if the process dies; the lock file contains
+// the pid and the host of the process for the error message
+func (w *Wallet) Lock() error {
+	if w.ReadOnly || w.lockFile != nil {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.LockPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = lockFile(file)
+	if err == errLocked {
+		file.Close()
+		var lock LockError
+		content, _ := ioutil.ReadFile(w.LockPath())
+		if _, err := fmt.Sscanf(string(content), "%d %s", &lock.PID, &lock.Host); err != nil {
+			lock = LockError{}
+		}
+		return &lock
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(fmt.Sprintf("%d %s\n", os.Getpid(), hostname)), 0)
+	}
+	if err != nil {
+		unlockFile(file)
+		file.Close()
+		return err
+	}
+
+	w.lockFile = file
+	return nil
+}
+
+// Unlock the wallet if the current process has the lock, the lock file is
+// kept to never remove a file locked by another process
+func (w *Wallet) Unlock() error {
+	if w.lockFile == nil {
+		return nil
+	}
+
+	file := w.lockFile
+	w.lockFile = nil
+	file.Truncate(0)
+	err := unlockFile(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,129 @@
+package gpm
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func generateLockedWallet(pid int) (Wallet, string) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "gpm_test-")
+	hostname, _ := os.Hostname()
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = filepath.Join(dir, "test.gpm")
+	wallet.Passphrase = "secret"
+	wallet.Save()
+	wallet.Unlock()
+
+	ioutil.WriteFile(wallet.LockPath(), []byte(fmt.Sprintf("%d %s\n", pid, hostname)), 0600)
+
+	return Wallet{Path: wallet.Path, Passphrase: wallet.Passphrase}, dir
+}
+
+func TestLockAndUnlock(t *testing.T) {
+	wallet, dir := generateLockedWallet(0)
+	defer os.RemoveAll(dir)
+	os.Remove(wallet.LockPath())
+
+	err := wallet.Load()
+	if err != nil {
+		t.Errorf("load wallet mustn't return an error: %s", err)
+	}
+
+	_, err = os.Stat(wallet.LockPath())
+	if err != nil {
+		t.Errorf("load wallet must create a lock file: %s", err)
+	}
+
+	other := Wallet{Path: wallet.Path, Passphrase: wallet.Passphrase}
+	err = other.Lock()
+	if err == nil {
+		t.Error("lock a wallet locked by another wallet must return an error")
+	}
+
+	wallet.Unlock()
+	err = other.Lock()
+	if err != nil {
+		t.Errorf("lock an unlocked wallet mustn't return an error: %s", err)
+	}
+	other.Unlock()
+}
+
+func TestLoadWalletLockedByAnotherProcess(t *testing.T) {
+	var lockErr *LockError
+
+	wallet, dir := generateLockedWallet(0)
+	defer os.RemoveAll(dir)
+
+	holder := Wallet{Path: wallet.Path, Passphrase: wallet.Passphrase}
+	err := holder.Lock()
+	if err != nil {
+		t.Fatalf("lock the wallet mustn't return an error: %s", err)
+	}
+	defer holder.Unlock()
+
+	err = wallet.Load()
+	if !errors.As(err, &lockErr) {
+		t.Errorf("load a locked wallet must return a lock error: %s", err)
+	} else if lockErr.PID != os.Getpid() {
+		t.Errorf("the lock error must contain the pid %d: %d", os.Getpid(), lockErr.PID)
+	}
+
+	wallet.ReadOnly = true
+	err = wallet.Load()
+	if err != nil {
+		t.Errorf("load a locked wallet in read-only mode mustn't return an error: %s", err)
+	}
+
+	entries := len(wallet.Entries)
+	if entries != 10 {
+		t.Errorf("must have 10 entries: %d", entries)
+	}
+
+	err = wallet.Save()
+	if err == nil {
+		t.Error("save a wallet in read-only mode must return an error")
+	}
+}
+
+func TestLoadWalletWithStaleLock(t *testing.T) {
+	wallet, dir := generateLockedWallet(2147483646)
+	defer os.RemoveAll(dir)
+
+	err := wallet.Load()
+	if err != nil {
+		t.Errorf("load a wallet with a stale lock mustn't return an error: %s", err)
+	}
+
+	err = wallet.Save()
+	if err != nil {
+		t.Errorf("save a wallet with a stale lock mustn't return an error: %s", err)
+	}
+	wallet.Unlock()
+}
+
+func TestLoadWalletWithEmptyLock(t *testing.T) {
+	wallet, dir := generateLockedWallet(0)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(wallet.LockPath(), []byte{}, 0600)
+
+	holder := Wallet{Path: wallet.Path, Passphrase: wallet.Passphrase}
+	holder.Lock()
+	holder.lockFile.Truncate(0)
+
+	err := wallet.Load()
+	if _, ok := err.(*LockError); !ok {
+		t.Errorf("a lock without pid must be held: %v", err)
+	}
+
+	holder.Unlock()
+	err = wallet.Load()
+	if err != nil {
+		t.Errorf("an empty lock file without lock mustn't return an error: %s", err)
+	}
+	wallet.Unlock()
+}
@@ -0,0 +1,182 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"reflect"
+)
+
+// MergeConflict is an entry changed in the both versions, a nil entry
+// means the entry has been deleted in this version
+type MergeConflict struct {
+	ID     string
+	Ours   *Entry
+	Theirs *Entry
+}
+
+// MergeResolver choose the entry to keep for a conflict, nil to delete it
+type MergeResolver func(conflict MergeConflict) *Entry
+
+// MergeResult contains the merged entries and the changes from their version
+type MergeResult struct {
+	Entries   []Entry
+	Added     []Entry
+	Updated   []Entry
+	Deleted   []Entry
+	Conflicts []MergeConflict
+	Trash     []Entry
+}
+
+// Name return the name of the entry in conflict
+func (c *MergeConflict) Name() string {
+	if c.Ours != nil {
+		return c.Ours.Name
+	}
+	if c.Theirs != nil {
+		return c.Theirs.Name
+	}
+
+	return c.ID
+}
+
+// ResolveNewest keep the last updated entry, a modified entry is always
+// kept rather than a deleted entry
+func ResolveNewest(conflict MergeConflict) *Entry {
+	switch {
+	case conflict.Ours == nil:
+		return conflict.Theirs
+	case conflict.Theirs == nil:
+		return conflict.Ours
+	case conflict.Theirs.LastUpdate > conflict.Ours.LastUpdate:
+		return conflict.Theirs
+	}
+
+	return conflict.Ours
+}
+
+// MergeEntries do a three-way merge of the entries with the ID, the base is
+// the common ancestor of our and their entries
+func MergeEntries(base []Entry, ours []Entry, theirs []Entry, resolve MergeResolver) MergeResult {
+	var result MergeResult
+
+	if resolve == nil {
+		resolve = ResolveNewest
+	}
+
+	baseEntries := entriesByID(base)
+	ourEntries := entriesByID(ours)
+	theirEntries := entriesByID(theirs)
+
+	for _, entry := range ours {
+		entry := entry
+		baseEntry, inBase := baseEntries[entry.ID]
+		theirEntry, inTheirs := theirEntries[entry.ID]
+
+		switch {
+		case !inTheirs && !inBase:
+			result.Entries = append(result.Entries, entry)
+		case !inTheirs && reflect.DeepEqual(entry, baseEntry):
+			result.Deleted = append(result.Deleted, entry)
+		case !inTheirs:
+			result.resolve(MergeConflict{ID: entry.ID, Ours: &entry}, resolve)
+		case reflect.DeepEqual(entry, theirEntry):
+			result.Entries = append(result.Entries, entry)
+		case inBase && reflect.DeepEqual(entry, baseEntry):
+			result.Entries = append(result.Entries, theirEntry)
+			result.Updated = append(result.Updated, theirEntry)
+		case inBase && reflect.DeepEqual(theirEntry, baseEntry):
+			result.Entries = append(result.Entries, entry)
+		default:
+			result.resolve(MergeConflict{ID: entry.ID, Ours: &entry, Theirs: &theirEntry}, resolve)
+		}
+	}
+
+	for _, entry := range theirs {
+		if _, ok := ourEntries[entry.ID]; ok {
+			continue
+		}
+
+		entry := entry
+		baseEntry, inBase := baseEntries[entry.ID]
+		switch {
+		case !inBase:
+			result.Entries = append(result.Entries, entry)
+			result.Added = append(result.Added, entry)
+		case !reflect.DeepEqual(entry, baseEntry):
+			result.resolve(MergeConflict{ID: entry.ID, Theirs: &entry}, resolve)
+		}
+	}
+
+	return result
+}
+
+// mergeWalletData merge the entries and the trash, an entry restored or
+// updated in a version is removed from the trash
+func mergeWalletData(base WalletData, ours WalletData, theirs WalletData, resolve MergeResolver) MergeResult {
+	result := MergeEntries(base.Entries, ours.Entries, theirs.Entries, resolve)
+	trash := MergeEntries(base.Trash, ours.Trash, theirs.Trash, nil)
+
+	entries := entriesByID(result.Entries)
+	for _, entry := range trash.Entries {
+		if _, ok := entries[entry.ID]; !ok {
+			result.Trash = append(result.Trash, entry)
+		}
+	}
+
+	return result
+}
+
+// resolve a conflict and add the chosen entry
+func (r *MergeResult) resolve(conflict MergeConflict, resolve MergeResolver) {
+	r.Conflicts = append(r.Conflicts, conflict)
+
+	entry := resolve(conflict)
+	if entry != nil {
+		r.Entries = append(r.Entries, *entry)
+	}
+}
+
+// entriesByID return a map of the entries with the ID as key
+func entriesByID(entries []Entry) map[string]Entry {
+	index := make(map[string]Entry)
+	for _, entry := range entries {
+		index[entry.ID] = entry
+	}
+
+	return index
+}
+
+// MergeBase guess the common ancestor of two versions without history, the
+// older version of an entry with the same creation date is the ancestor
+func MergeBase(ours []Entry, theirs []Entry) []Entry {
+	var base []Entry
+
+	theirEntries := entriesByID(theirs)
+	for _, entry := range ours {
+		theirEntry, ok := theirEntries[entry.ID]
+		if !ok || theirEntry.Create != entry.Create {
+			continue
+		}
+
+		switch {
+		case reflect.DeepEqual(entry, theirEntry), entry.LastUpdate < theirEntry.LastUpdate:
+			base = append(base, entry)
+		case entry.LastUpdate > theirEntry.LastUpdate:
+			base = append(base, theirEntry)
+		}
+	}
+
+	return base
+}
@@ -0,0 +1,132 @@
+package gpm
+
+import (
+	"testing"
+)
+
+func generateMergeEntries() []Entry {
+	return []Entry{
+		{ID: "1", Name: "Entry 1", LastUpdate: 1},
+		{ID: "2", Name: "Entry 2", LastUpdate: 1},
+		{ID: "3", Name: "Entry 3", LastUpdate: 1},
+	}
+}
+
+func TestMergeWithoutChange(t *testing.T) {
+	base := generateMergeEntries()
+	result := MergeEntries(base, generateMergeEntries(), generateMergeEntries(), nil)
+
+	if len(result.Entries) != 3 {
+		t.Errorf("must have 3 entries: %d", len(result.Entries))
+	}
+	if len(result.Added)+len(result.Updated)+len(result.Deleted)+len(result.Conflicts) != 0 {
+		t.Error("mustn't have changes")
+	}
+}
+
+func TestMergeChangesFromBothSides(t *testing.T) {
+	base := generateMergeEntries()
+	ours := generateMergeEntries()
+	theirs := generateMergeEntries()
+
+	ours[0].Name = "Entry 1 ours"
+	ours = append(ours, Entry{ID: "4", Name: "Entry 4"})
+	theirs[1].Name = "Entry 2 theirs"
+	theirs = append(theirs[:2], Entry{ID: "5", Name: "Entry 5"})
+
+	result := MergeEntries(base, ours, theirs, nil)
+	entries := entriesByID(result.Entries)
+	if len(result.Entries) != 4 {
+		t.Errorf("must have 4 entries: %d", len(result.Entries))
+	}
+	if entries["1"].Name != "Entry 1 ours" {
+		t.Errorf("our update must be kept: %s", entries["1"].Name)
+	}
+	if entries["2"].Name != "Entry 2 theirs" {
+		t.Errorf("their update must be kept: %s", entries["2"].Name)
+	}
+	if _, ok := entries["3"]; ok {
+		t.Error("the entry deleted by them must be deleted")
+	}
+	if len(result.Added) != 1 || len(result.Updated) != 1 || len(result.Deleted) != 1 {
+		t.Errorf("must have 1 added, 1 updated and 1 deleted entries: %d %d %d",
+			len(result.Added), len(result.Updated), len(result.Deleted))
+	}
+	if len(result.Conflicts) != 0 {
+		t.Errorf("mustn't have conflicts: %d", len(result.Conflicts))
+	}
+}
+
+func TestMergeConflictKeepNewest(t *testing.T) {
+	base := generateMergeEntries()
+	ours := generateMergeEntries()
+	theirs := generateMergeEntries()
+
+	ours[0].Name, ours[0].LastUpdate = "Entry 1 ours", 3
+	theirs[0].Name, theirs[0].LastUpdate = "Entry 1 theirs", 2
+	ours[1].Name, ours[1].LastUpdate = "Entry 2 ours", 2
+	theirs[1].Name, theirs[1].LastUpdate = "Entry 2 theirs", 3
+
+	result := MergeEntries(base, ours, theirs, nil)
+	entries := entriesByID(result.Entries)
+	if len(result.Conflicts) != 2 {
+		t.Errorf("must have 2 conflicts: %d", len(result.Conflicts))
+	}
+	if entries["1"].Name != "Entry 1 ours" {
+		t.Errorf("the newest entry must be kept: %s", entries["1"].Name)
+	}
+	if entries["2"].Name != "Entry 2 theirs" {
+		t.Errorf("the newest entry must be kept: %s", entries["2"].Name)
+	}
+}
+
+func TestMergeConflictDeletedAndUpdated(t *testing.T) {
+	base := generateMergeEntries()
+	ours := generateMergeEntries()
+	theirs := generateMergeEntries()
+
+	ours[0].Name = "Entry 1 ours"
+	theirs = theirs[1:]
+
+	result := MergeEntries(base, ours, theirs, nil)
+	if len(result.Conflicts) != 1 {
+		t.Errorf("must have 1 conflict: %d", len(result.Conflicts))
+	}
+	if len(result.Entries) != 3 {
+		t.Errorf("the updated entry must be kept: %d", len(result.Entries))
+	}
+
+	result = MergeEntries(base, ours, theirs, func(conflict MergeConflict) *Entry {
+		return conflict.Theirs
+	})
+	if len(result.Entries) != 2 {
+		t.Errorf("the entry must be deleted by the resolver: %d", len(result.Entries))
+	}
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	ours := generateMergeEntries()
+	theirs := generateMergeEntries()
+
+	ours[0].Name, ours[0].LastUpdate = "Entry 1 ours", 2
+	theirs[1].Name, theirs[1].LastUpdate = "Entry 2 theirs", 2
+	theirs[2].Name = "Entry 3 theirs"
+	ours = append(ours, Entry{ID: "4", Name: "Entry 4"})
+	theirs = append(theirs, Entry{ID: "5", Name: "Entry 5"})
+
+	result := MergeEntries(MergeBase(ours, theirs), ours, theirs, nil)
+	entries := entriesByID(result.Entries)
+	if len(result.Entries) != 5 {
+		t.Errorf("must have 5 entries: %d", len(result.Entries))
+	}
+	if entries["1"].Name != "Entry 1 ours" || entries["2"].Name != "Entry 2 theirs" {
+		t.Errorf("the last updated entries must be kept: %s %s", entries["1"].Name, entries["2"].Name)
+	}
+	if len(result.Added) != 1 || len(result.Updated) != 1 || len(result.Deleted) != 0 {
+		t.Errorf("must have 1 added, 1 updated and 0 deleted entries: %d %d %d",
+			len(result.Added), len(result.Updated), len(result.Deleted))
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].ID != "3" {
+		t.Errorf("an entry updated at the same time must be a conflict: %v", result.Conflicts)
+	}
+}
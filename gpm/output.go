@@ -0,0 +1,211 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats, text is an alias of table
+const (
+	OutputTable = "table"
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// OutputFormats is the list of the output formats
+var OutputFormats = []string{OutputTable, OutputJSON, OutputYAML}
+
+// EntryOutput is the schema of an entry in the json and yaml outputs, the
+// password, the OTP key and the secret fields are empty unless requested
+type EntryOutput struct {
+	ID          string             `json:"id" yaml:"id"`
+	Name        string             `json:"name" yaml:"name"`
+	Group       string             `json:"group" yaml:"group"`
+	Tags        []string           `json:"tags" yaml:"tags"`
+	URI         string             `json:"uri" yaml:"uri"`
+	User        string             `json:"user" yaml:"user"`
+	Password    string             `json:"password,omitempty" yaml:"password,omitempty"`
+	OTP         bool               `json:"otp" yaml:"otp"`
+	OTPKey      string             `json:"otp_key,omitempty" yaml:"otp_key,omitempty"`
+	Comment     string             `json:"comment" yaml:"comment"`
+	Fields      []FieldOutput      `json:"fields" yaml:"fields"`
+	Attachments []AttachmentOutput `json:"attachments" yaml:"attachments"`
+	Expire      string             `json:"expire,omitempty" yaml:"expire,omitempty"`
+	Expired     bool               `json:"expired" yaml:"expired"`
+	Created     string             `json:"created" yaml:"created"`
+	Updated     string             `json:"updated" yaml:"updated"`
+}
+
+// FieldOutput is the schema of a custom field, the value of a secret field
+// is empty unless requested
+type FieldOutput struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// AttachmentOutput is the schema of an attachment, without the content
+type AttachmentOutput struct {
+	Name string `json:"name" yaml:"name"`
+	MIME string `json:"mime" yaml:"mime"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// GroupOutput is the schema of a group with the number of entries in the
+// group and its sub groups
+type GroupOutput struct {
+	Name    string `json:"name" yaml:"name"`
+	Depth   int    `json:"depth" yaml:"depth"`
+	Entries int    `json:"entries" yaml:"entries"`
+}
+
+// OTPOutput is the schema of an OTP code with the seconds before it changes
+type OTPOutput struct {
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	Code      string `json:"code" yaml:"code"`
+	Remaining int64  `json:"remaining" yaml:"remaining"`
+}
+
+// NewEntryOutput return the schema of an entry, with the secrets if secrets is true
+func NewEntryOutput(entry Entry, secrets bool) EntryOutput {
+	output := EntryOutput{
+		ID:          entry.ID,
+		Name:        entry.Name,
+		Group:       entry.Group,
+		Tags:        append([]string{}, entry.Tags...),
+		URI:         entry.URI,
+		User:        entry.User,
+		OTP:         entry.OTP != "",
+		Comment:     entry.Comment,
+		Fields:      []FieldOutput{},
+		Attachments: []AttachmentOutput{},
+		Expired:     entry.Expired(time.Now()),
+		Created:     time.Unix(entry.Create, 0).Format(time.RFC3339),
+		Updated:     time.Unix(entry.LastUpdate, 0).Format(time.RFC3339),
+	}
+
+	if secrets {
+		output.Password = entry.Password
+		output.OTPKey = entry.OTP
+	}
+	if expire := entry.ExpireDate(); expire > 0 {
+		output.Expire = time.Unix(expire, 0).Format(ExpireDateFormat)
+	}
+	for _, field := range entry.Fields {
+		fieldOutput := FieldOutput{Name: field.Name, Type: field.Type, Value: field.Value}
+		if field.Secret() && !secrets {
+			fieldOutput.Value = ""
+		}
+		output.Fields = append(output.Fields, fieldOutput)
+	}
+	output.Attachments = NewAttachmentsOutput(entry.Attachments)
+
+	return output
+}
+
+// NewEntriesOutput return the schema of the entries
+func NewEntriesOutput(entries []Entry, secrets bool) []EntryOutput {
+	output := []EntryOutput{}
+	for _, entry := range entries {
+		output = append(output, NewEntryOutput(entry, secrets))
+	}
+
+	return output
+}
+
+// NewAttachmentsOutput return the schema of the attachments
+func NewAttachmentsOutput(attachments []Attachment) []AttachmentOutput {
+	output := []AttachmentOutput{}
+	for _, attachment := range attachments {
+		output = append(output, AttachmentOutput{Name: attachment.Name, MIME: attachment.MIME, Size: attachment.Size})
+	}
+
+	return output
+}
+
+// NewOTPOutput return the schema of the current OTP code of an entry
+func NewOTPOutput(entry Entry) (OTPOutput, error) {
+	if entry.OTP == "" {
+		return OTPOutput{}, fmt.Errorf("the entry %s hasn't an OTP key", entry.Name)
+	}
+
+	code, remaining, err := entry.OTPCode()
+	if err != nil {
+		return OTPOutput{}, err
+	}
+
+	return OTPOutput{ID: entry.ID, Name: entry.Name, Code: code, Remaining: remaining}, nil
+}
+
+// GroupsOutput return the schema of the groups tree
+func (w *Wallet) GroupsOutput() []GroupOutput {
+	output := []GroupOutput{}
+	for _, group := range w.GroupTree() {
+		count := 0
+		for _, entry := range w.Entries {
+			if InGroup(entry.Group, group) {
+				count++
+			}
+		}
+		output = append(output, GroupOutput{Name: group, Depth: GroupDepth(group), Entries: count})
+	}
+
+	return output
+}
+
+// CheckOutput return an error if the output format is unknown
+func CheckOutput(format string) error {
+	switch format {
+	case OutputTable, OutputText, OutputJSON, OutputYAML:
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format %s, it must be one of: %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// WriteOutput write the data in json or yaml, the table format is written
+// by the table function
+func WriteOutput(output io.Writer, format string, data interface{}, table func(output io.Writer) error) error {
+	switch format {
+	case OutputTable, OutputText:
+		return table(output)
+	case OutputJSON:
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(output, string(content))
+		return err
+	case OutputYAML:
+		encoder := yaml.NewEncoder(output)
+		encoder.SetIndent(2)
+		err := encoder.Encode(data)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	return CheckOutput(format)
+}
@@ -0,0 +1,136 @@
+package gpm
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEntryOutputSecrets(t *testing.T) {
+	entry := Entry{
+		ID:       "1",
+		Name:     "test",
+		Password: "s3cr3t",
+		OTP:      "JBSWY3DPEHPK3PXP",
+		Fields: []CustomField{
+			{Name: "PIN", Type: FieldSecret, Value: "1234"},
+			{Name: "Question", Type: FieldText, Value: "first pet"},
+		},
+	}
+
+	output := NewEntryOutput(entry, false)
+	if output.Password != "" || output.OTPKey != "" || output.Fields[0].Value != "" {
+		t.Errorf("the secrets mustn't be in the output: %+v", output)
+	}
+	if !output.OTP || output.Fields[1].Value != "first pet" {
+		t.Errorf("the other values must be in the output: %+v", output)
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("the output must be marshaled: %s", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "1234") {
+		t.Errorf("the json output mustn't contain the secrets: %s", data)
+	}
+
+	output = NewEntryOutput(entry, true)
+	if output.Password != "s3cr3t" || output.OTPKey != entry.OTP || output.Fields[0].Value != "1234" {
+		t.Errorf("the secrets must be in the output: %+v", output)
+	}
+}
+
+func TestEntryOutputSchema(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := WriteOutput(&buffer, OutputJSON, NewEntriesOutput([]Entry{{ID: "1", Name: "test"}}, false), nil)
+	if err != nil {
+		t.Fatalf("the json output mustn't return an error: %s", err)
+	}
+
+	var entries []map[string]interface{}
+	err = json.Unmarshal(buffer.Bytes(), &entries)
+	if err != nil {
+		t.Fatalf("the json output must be valid: %s", err)
+	}
+	for _, key := range []string{"id", "name", "group", "tags", "uri", "user", "otp", "comment", "fields", "attachments", "expired", "created", "updated"} {
+		if _, ok := entries[0][key]; !ok {
+			t.Errorf("the json output must contain the key %s", key)
+		}
+	}
+	if tags, ok := entries[0]["tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("the tags must be an empty list: %v", entries[0]["tags"])
+	}
+
+	buffer.Reset()
+	err = WriteOutput(&buffer, OutputYAML, NewEntriesOutput([]Entry{{ID: "1", Name: "test"}}, false), nil)
+	if err != nil {
+		t.Fatalf("the yaml output mustn't return an error: %s", err)
+	}
+
+	entries = nil
+	err = yaml.Unmarshal(buffer.Bytes(), &entries)
+	if err != nil || len(entries) != 1 || entries[0]["name"] != "test" {
+		t.Errorf("the yaml output must be valid: %v %s", err, buffer.String())
+	}
+}
+
+func TestWriteOutputTable(t *testing.T) {
+	var buffer bytes.Buffer
+
+	for _, format := range []string{OutputTable, OutputText} {
+		called := false
+		err := WriteOutput(&buffer, format, nil, func(output io.Writer) error {
+			called = true
+			return nil
+		})
+		if err != nil || !called {
+			t.Errorf("the %s format must call the table function: %v", format, err)
+		}
+	}
+
+	err := WriteOutput(&buffer, "xml", nil, nil)
+	if err == nil {
+		t.Error("an unknown format must return an error")
+	}
+}
+
+func TestGroupsOutput(t *testing.T) {
+	wallet := Wallet{Entries: []Entry{
+		{ID: "1", Name: "a", Group: "work/aws"},
+		{ID: "2", Name: "b", Group: "work"},
+		{ID: "3", Name: "c", Group: "perso"},
+	}}
+
+	groups := wallet.GroupsOutput()
+	if len(groups) != 3 {
+		t.Fatalf("must return all the groups of the tree: %v", groups)
+	}
+	for _, group := range groups {
+		if group.Name == "work" && group.Entries != 2 {
+			t.Errorf("a group must count the entries of its sub groups: %d", group.Entries)
+		}
+		if group.Name == "work/aws" && group.Depth != 1 {
+			t.Errorf("the depth of a sub group must be 1: %d", group.Depth)
+		}
+	}
+}
+
+func TestOTPOutput(t *testing.T) {
+	_, err := NewOTPOutput(Entry{Name: "test"})
+	if err == nil {
+		t.Error("an entry without OTP key must return an error")
+	}
+
+	otp, err := NewOTPOutput(Entry{Name: "test", OTP: "JBSWY3DPEHPK3PXP"})
+	if err != nil {
+		t.Fatalf("must generate an OTP code without error: %s", err)
+	}
+	if len(otp.Code) != 6 || otp.Remaining <= 0 || otp.Remaining > 30 {
+		t.Errorf("must return the code and the remaining seconds: %+v", otp)
+	}
+}
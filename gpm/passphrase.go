@@ -0,0 +1,53 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed wordlist.txt
+var wordList string
+
+// Words return the list of words used to generate the passphrases
+func Words() []string {
+	return strings.Fields(wordList)
+}
+
+// RandomPassphrase generate a passphrase with random words, empty if the
+// length is lower than 1
+func RandomPassphrase(length int, separator string, capitalize bool, digit bool) string {
+	if length < 1 {
+		return ""
+	}
+
+	words := Words()
+	passphrase := make([]string, length)
+
+	for i := range passphrase {
+		passphrase[i] = words[randomInt(len(words))]
+		if capitalize {
+			passphrase[i] = strings.ToUpper(passphrase[i][:1]) + passphrase[i][1:]
+		}
+	}
+
+	if digit && length > 0 {
+		i := randomInt(length)
+		passphrase[i] = passphrase[i] + string(Digits[randomInt(len(Digits))])
+	}
+
+	return strings.Join(passphrase, separator)
+}
@@ -0,0 +1,57 @@
+package gpm
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	words := Words()
+	if len(words) < 2048 {
+		t.Errorf("the word list must contain at least 2048 words: %d", len(words))
+	}
+
+	exist := make(map[string]bool)
+	for _, word := range words {
+		if exist[word] {
+			t.Errorf("the word list mustn't contain duplicates: %s", word)
+		}
+		exist[word] = true
+	}
+}
+
+func TestRandomPassphrase(t *testing.T) {
+	passphrase := RandomPassphrase(6, "-", false, false)
+	words := strings.Split(passphrase, "-")
+	if len(words) != 6 {
+		t.Errorf("the passphrase must have 6 words: %s", passphrase)
+	}
+
+	r := regexp.MustCompile(`^[a-z]+(-[a-z]+){5}$`)
+	if !r.MatchString(passphrase) {
+		t.Errorf("the passphrase must contain only lowercase words: %s", passphrase)
+	}
+}
+
+func TestRandomPassphraseWithCapitalizeAndDigit(t *testing.T) {
+	passphrase := RandomPassphrase(4, " ", true, true)
+	r := regexp.MustCompile(`^[A-Z][a-z]+[0-9]?( [A-Z][a-z]+[0-9]?){3}$`)
+	if !r.MatchString(passphrase) {
+		t.Errorf("the passphrase must contain capitalized words: %s", passphrase)
+	}
+
+	digits := regexp.MustCompile(`[0-9]`).FindAllString(passphrase, -1)
+	if len(digits) != 1 {
+		t.Errorf("the passphrase must contain one digit: %s", passphrase)
+	}
+}
+
+func TestRandomPassphraseWithoutWords(t *testing.T) {
+	for _, length := range []int{0, -1} {
+		passphrase := RandomPassphrase(length, "-", false, true)
+		if passphrase != "" {
+			t.Errorf("a passphrase without words must be empty: %s", passphrase)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ambiguous contains the look-alike chars
+const Ambiguous = "0OoIl1|`'\""
+
+// PasswordProfile contains the settings to generate a password
+type PasswordProfile struct {
+	Name        string `json:"name"`
+	Length      int    `json:"length"`
+	Letter      bool   `json:"letter"`
+	Digit       bool   `json:"digit"`
+	Special     bool   `json:"special"`
+	Specials    string `json:"specials,omitempty"`
+	NoAmbiguous bool   `json:"no_ambiguous"`
+}
+
+// Classes return the chars classes enabled in the profile
+func (p *PasswordProfile) Classes() ([]string, error) {
+	var classes []string
+
+	specials := Specials
+	if p.Specials != "" {
+		specials = p.Specials
+	}
+
+	if p.Letter {
+		classes = append(classes, Letters)
+	}
+	if p.Digit {
+		classes = append(classes, Digits)
+	}
+	if p.Special {
+		classes = append(classes, specials)
+	}
+	if !p.Letter && !p.Digit && !p.Special {
+		classes = []string{Digits, Letters}
+	}
+
+	for i, class := range classes {
+		if p.NoAmbiguous {
+			class = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(Ambiguous, r) {
+					return -1
+				}
+				return r
+			}, class)
+		}
+
+		if class == "" {
+			return []string{}, fmt.Errorf("the profile %s has an empty chars class", p.Name)
+		}
+		classes[i] = class
+	}
+
+	return classes, nil
+}
+
+// Generate a random password with the profile
+func (p *PasswordProfile) Generate() (string, error) {
+	if p.Length <= 0 {
+		return "", fmt.Errorf("the profile %s must have a length greater than 0", p.Name)
+	}
+
+	classes, err := p.Classes()
+	if err != nil {
+		return "", err
+	}
+
+	return randomString(p.Length, classes), nil
+}
@@ -0,0 +1,53 @@
+package gpm
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWithPinProfile(t *testing.T) {
+	profile := PasswordProfile{Name: "pin", Length: 6, Digit: true}
+	password, err := profile.Generate()
+	if err != nil {
+		t.Errorf("generate a password mustn't return an error: %s", err)
+	}
+
+	r := regexp.MustCompile(`^[0-9]{6}$`)
+	if !r.MatchString(password) {
+		t.Errorf("the password must contain 6 digits: %s", password)
+	}
+}
+
+func TestGenerateWithCustomSpecials(t *testing.T) {
+	profile := PasswordProfile{Name: "bank", Length: 64, Special: true, Specials: "#!"}
+	password, _ := profile.Generate()
+
+	r := regexp.MustCompile(`^[#!]{64}$`)
+	if !r.MatchString(password) {
+		t.Errorf("the password must contain only the custom specials chars: %s", password)
+	}
+}
+
+func TestGenerateWithoutAmbiguous(t *testing.T) {
+	profile := PasswordProfile{Name: "bank", Length: 256, Letter: true, Digit: true, Special: true, NoAmbiguous: true}
+	password, _ := profile.Generate()
+
+	if strings.ContainsAny(password, Ambiguous) {
+		t.Errorf("the password mustn't contain ambiguous chars: %s", password)
+	}
+}
+
+func TestGenerateWithBadProfile(t *testing.T) {
+	profile := PasswordProfile{Name: "bad"}
+	_, err := profile.Generate()
+	if err == nil {
+		t.Error("a profile without length must return an error")
+	}
+
+	profile = PasswordProfile{Name: "bad", Length: 8, Special: true, Specials: "|", NoAmbiguous: true}
+	_, err = profile.Generate()
+	if err == nil {
+		t.Error("a profile with an empty chars class must return an error")
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed passwords.txt
+var commonPasswords string
+
+// Keyboard rows used to detect the keyboard patterns
+var keyboardRows = []string{
+	"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm",
+	"azertyuiop", "qsdfghjklm", "wxcvbn",
+}
+
+// Leet speak substitutions
+var leetChars = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i',
+	'!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+var (
+	dateWithSeparator = regexp.MustCompile(`^(\d{1,4})([-/. ])(\d{1,2})([-/. ])(\d{1,4})$`)
+	dictionary        map[string]int
+	dictionarySize    int
+	dictionaryOnce    sync.Once
+)
+
+// Strength contains the estimation of a password strength
+type Strength struct {
+	Entropy float64
+	Score   int
+	Warning string
+}
+
+// strengthMatch is a pattern found in the password
+type strengthMatch struct {
+	start   int
+	end     int
+	entropy float64
+	warning string
+}
+
+// Label return the score as text
+func (s *Strength) Label() string {
+	return []string{"very weak", "weak", "fair", "strong", "very strong"}[s.Score]
+}
+
+// Color return the termui color of the score
+func (s *Strength) Color() string {
+	return []string{"red", "red", "yellow", "green", "green"}[s.Score]
+}
+
+// PasswordStrength estimate the entropy of a password with the cheapest
+// decomposition in known patterns (dictionary words, sequences, keyboard
+// patterns, repeats and dates) and random chars
+func PasswordStrength(password string) Strength {
+	chars := []rune(password)
+	if len(chars) == 0 {
+		return Strength{Warning: "the password is empty"}
+	}
+
+	matches := passwordMatches(chars)
+	charEntropy := math.Log2(float64(cardinality(chars)))
+	best := make([]float64, len(chars)+1)
+	from := make([]*strengthMatch, len(chars)+1)
+
+	for end := 1; end <= len(chars); end++ {
+		best[end] = best[end-1] + charEntropy
+		from[end] = nil
+		for i := range matches {
+			m := &matches[i]
+			if m.end == end && best[m.start]+m.entropy < best[end] {
+				best[end] = best[m.start] + m.entropy
+				from[end] = m
+			}
+		}
+	}
+
+	strength := Strength{Entropy: best[len(chars)]}
+	for end := len(chars); end > 0; {
+		if from[end] == nil {
+			end--
+			continue
+		}
+		if strength.Warning == "" {
+			strength.Warning = from[end].warning
+		}
+		end = from[end].start
+	}
+
+	switch {
+	case strength.Entropy < 28:
+		strength.Score = 0
+	case strength.Entropy < 36:
+		strength.Score = 1
+	case strength.Entropy < 60:
+		strength.Score = 2
+	case strength.Entropy < 80:
+		strength.Score = 3
+	default:
+		strength.Score = 4
+	}
+
+	if strength.Warning == "" && strength.Score < 2 {
+		strength.Warning = "use a longer password"
+	}
+
+	return strength
+}
+
+// passwordMatches return all the patterns found in the password
+func passwordMatches(chars []rune) []strengthMatch {
+	var matches []strengthMatch
+
+	for start := 0; start < len(chars); start++ {
+		for end := start + 3; end <= len(chars) && end-start <= 32; end++ {
+			token := chars[start:end]
+			if entropy, warning, ok := dictionaryMatch(token); ok {
+				matches = append(matches, strengthMatch{start, end, entropy, warning})
+			}
+			if entropy, ok := sequenceMatch(token); ok {
+				matches = append(matches, strengthMatch{start, end, entropy, "sequences like abc or 6543 are easy to guess"})
+			}
+			if entropy, ok := keyboardMatch(token); ok {
+				matches = append(matches, strengthMatch{start, end, entropy, "keyboard patterns are easy to guess"})
+			}
+			if entropy, ok := repeatMatch(token); ok {
+				matches = append(matches, strengthMatch{start, end, entropy, "repeats like aaa or abcabc are easy to guess"})
+			}
+			if entropy, ok := dateMatch(string(token)); ok {
+				matches = append(matches, strengthMatch{start, end, entropy, "dates are easy to guess"})
+			}
+		}
+	}
+
+	return matches
+}
+
+// dictionaryMatch check if the token is a common password or a word, even
+// with uppercase chars or leet speak substitutions
+func dictionaryMatch(token []rune) (float64, string, bool) {
+	dictionaryOnce.Do(func() {
+		passwords := strings.Fields(commonPasswords)
+		dictionary = make(map[string]int)
+		for _, word := range append(passwords, Words()...) {
+			if _, ok := dictionary[word]; !ok {
+				dictionary[word] = len(dictionary) + 1
+			}
+		}
+		dictionarySize = len(passwords)
+	})
+
+	upper, lower, substitutions := 0, 0, 0
+	word := make([]rune, len(token))
+	for i, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+
+		word[i] = unicode.ToLower(r)
+		if c, ok := leetChars[r]; ok {
+			word[i] = c
+			substitutions++
+		}
+	}
+
+	rank, ok := dictionary[strings.ToLower(string(token))]
+	if !ok {
+		rank, ok = dictionary[string(word)]
+	} else {
+		substitutions = 0
+	}
+	if !ok {
+		return 0, "", false
+	}
+
+	entropy := math.Log2(float64(rank)) + float64(substitutions)
+	if upper > 0 && lower > 0 && !(upper == 1 && unicode.IsUpper(token[0])) {
+		entropy += math.Log2(binomial(upper+lower, upper))
+	} else if upper > 0 {
+		entropy++
+	}
+
+	if rank <= dictionarySize {
+		return entropy, "this is a common password", true
+	}
+
+	return entropy, "dictionary words are easy to guess", true
+}
+
+// sequenceMatch check if the token is a sequence like abc or 9876
+func sequenceMatch(token []rune) (float64, bool) {
+	delta := token[1] - token[0]
+	if delta != 1 && delta != -1 {
+		return 0, false
+	}
+
+	for i := 2; i < len(token); i++ {
+		if token[i]-token[i-1] != delta || charClass(token[i]) != charClass(token[0]) {
+			return 0, false
+		}
+	}
+
+	entropy := math.Log2(float64(classSize(token[0])))
+	if strings.ContainsRune("aAzZ019", token[0]) {
+		entropy = 1
+	}
+	if delta < 0 {
+		entropy++
+	}
+
+	return entropy + math.Log2(float64(len(token))), true
+}
+
+// keyboardMatch check if the token is a part of a keyboard row
+func keyboardMatch(token []rune) (float64, bool) {
+	lower := strings.ToLower(string(token))
+	for _, row := range keyboardRows {
+		if strings.Contains(row, lower) || strings.Contains(row, reverse(lower)) {
+			return math.Log2(float64(len(row)*len(keyboardRows)*2)) + math.Log2(float64(len(token))), true
+		}
+	}
+
+	return 0, false
+}
+
+// repeatMatch check if the token is a repeated chunk like aaa or abcabc
+func repeatMatch(token []rune) (float64, bool) {
+	for size := 1; size <= len(token)/2; size++ {
+		if len(token)%size != 0 {
+			continue
+		}
+
+		chunk := string(token[:size])
+		if strings.Repeat(chunk, len(token)/size) != string(token) {
+			continue
+		}
+
+		entropy := math.Log2(float64(classSize(token[0])))
+		if size > 1 {
+			strength := PasswordStrength(chunk)
+			entropy = strength.Entropy
+		}
+
+		return entropy + math.Log2(float64(len(token)/size)), true
+	}
+
+	return 0, false
+}
+
+// dateMatch check if the token is a year or a date
+func dateMatch(token string) (float64, bool) {
+	if strings.Trim(token, Digits) == "" {
+		digits, _ := strconv.Atoi(token)
+		switch len(token) {
+		case 4:
+			return math.Log2(200), digits >= 1900 && digits < 2100
+		case 6:
+			return math.Log2(31 * 12 * 100), isDate(token[:2], token[2:4], token[4:]) ||
+				isDate(token[4:], token[2:4], token[:2]) || isDate(token[2:4], token[:2], token[4:])
+		case 8:
+			return math.Log2(31 * 12 * 200), isDate(token[:2], token[2:4], token[4:]) ||
+				isDate(token[6:], token[4:6], token[:4]) || isDate(token[2:4], token[:2], token[4:])
+		}
+
+		return 0, false
+	}
+
+	parts := dateWithSeparator.FindStringSubmatch(token)
+	if parts == nil || parts[2] != parts[4] {
+		return 0, false
+	}
+
+	entropy := math.Log2(31*12*200) + 2
+	if len(parts[1]) == 4 {
+		return entropy, isDate(parts[5], parts[3], parts[1])
+	}
+
+	return entropy, isDate(parts[1], parts[3], parts[5]) || isDate(parts[3], parts[1], parts[5])
+}
+
+// isDate check if the day, the month and the year are valid
+func isDate(day string, month string, year string) bool {
+	d, errDay := strconv.Atoi(day)
+	m, errMonth := strconv.Atoi(month)
+	y, errYear := strconv.Atoi(year)
+	if errDay != nil || errMonth != nil || errYear != nil {
+		return false
+	}
+
+	if len(year) == 4 && (y < 1900 || y >= 2100) {
+		return false
+	}
+
+	return d >= 1 && d <= 31 && m >= 1 && m <= 12 && (len(year) == 2 || len(year) == 4)
+}
+
+// cardinality return the size of the chars classes used by the password
+func cardinality(chars []rune) int {
+	classes := make(map[int]bool)
+	size := 0
+
+	for _, r := range chars {
+		class := charClass(r)
+		if !classes[class] {
+			classes[class] = true
+			size += classSize(r)
+		}
+	}
+
+	return size
+}
+
+// charClass return the class of a char: digit, lower, upper, special or unicode
+func charClass(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return 0
+	case r >= 'a' && r <= 'z':
+		return 1
+	case r >= 'A' && r <= 'Z':
+		return 2
+	case r < 128:
+		return 3
+	}
+
+	return 4
+}
+
+// classSize return the number of chars in the class of a char
+func classSize(r rune) int {
+	return []int{10, 26, 26, 33, 100}[charClass(r)]
+}
+
+// binomial return the number of combinations of k elements among n
+func binomial(n int, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+// reverse a string
+func reverse(s string) string {
+	chars := []rune(s)
+	for i, j := 0, len(chars)-1; i < j; i, j = i+1, j-1 {
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+
+	return string(chars)
+}
@@ -0,0 +1,64 @@
+package gpm
+
+import "testing"
+
+func TestStrengthOfEmptyPassword(t *testing.T) {
+	strength := PasswordStrength("")
+	if strength.Entropy != 0 || strength.Score != 0 {
+		t.Errorf("an empty password must have a null entropy: %f", strength.Entropy)
+	}
+}
+
+func TestStrengthOfCommonPasswords(t *testing.T) {
+	for _, password := range []string{"password", "P@ssw0rd", "123456789", "qwerty", "azerty"} {
+		strength := PasswordStrength(password)
+		if strength.Score != 0 {
+			t.Errorf("the password %s must be very weak: %s", password, strength.Label())
+		}
+		if strength.Warning == "" {
+			t.Errorf("the password %s must have a warning", password)
+		}
+	}
+}
+
+func TestStrengthOfPatterns(t *testing.T) {
+	patterns := map[string]string{
+		"abcdefgh":   "sequences like abc or 6543 are easy to guess",
+		"asdfghjk":   "keyboard patterns are easy to guess",
+		"zzzzzzzzzz": "repeats like aaa or abcabc are easy to guess",
+		"12/05/1987": "dates are easy to guess",
+		"Sunflower":  "dictionary words are easy to guess",
+	}
+
+	for password, warning := range patterns {
+		strength := PasswordStrength(password)
+		if strength.Score > 1 {
+			t.Errorf("the password %s must be weak: %s", password, strength.Label())
+		}
+		if strength.Warning != warning {
+			t.Errorf("the password %s must have the warning '%s': %s", password, warning, strength.Warning)
+		}
+	}
+}
+
+func TestStrengthOfRandomPassword(t *testing.T) {
+	password := RandomString(16, true, true, true)
+	strength := PasswordStrength(password)
+	if strength.Score < 3 {
+		t.Errorf("a random password with 16 chars must be strong: %s %s", password, strength.Label())
+	}
+
+	passphrase := RandomPassphrase(6, "-", false, false)
+	strength = PasswordStrength(passphrase)
+	if strength.Score < 3 {
+		t.Errorf("a random passphrase with 6 words must be strong: %s %s", passphrase, strength.Label())
+	}
+}
+
+func TestStrengthIncreaseWithLength(t *testing.T) {
+	short := PasswordStrength("x7#Kq")
+	long := PasswordStrength("x7#Kq9!vLm2$")
+	if short.Entropy >= long.Entropy {
+		t.Errorf("a longer password must have more entropy: %f >= %f", short.Entropy, long.Entropy)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"sort"
+	"strings"
+)
+
+// Tag is a tag name with the number of entries using it
+type Tag struct {
+	Name  string
+	Count int
+}
+
+// ParseTags return the tags from a comma separated list, without the
+// empty and duplicated tags
+func ParseTags(list string) []string {
+	var tags []string
+
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// HasTags return true if the entry has all the tags
+func (e *Entry) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		if !containsTag(e.Tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Tags return the tags used by the entries with the number of entries
+func (w *Wallet) Tags() []Tag {
+	var tags []Tag
+
+	for _, entry := range w.Entries {
+		for _, name := range entry.Tags {
+			found := false
+			for i := range tags {
+				if strings.EqualFold(tags[i].Name, name) {
+					tags[i].Count++
+					found = true
+					break
+				}
+			}
+			if !found {
+				tags = append(tags, Tag{Name: name, Count: 1})
+			}
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+
+	return tags
+}
+
+// containsTag return true if the tag is in the list, the case is ignored
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
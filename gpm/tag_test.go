@@ -0,0 +1,47 @@
+package gpm
+
+import (
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tags := ParseTags(" work, bank,,Work , 2fa ")
+	if len(tags) != 3 || tags[0] != "work" || tags[1] != "bank" || tags[2] != "2fa" {
+		t.Errorf("the tags must be trimmed without the empty and duplicated tags: %v", tags)
+	}
+}
+
+func TestWalletTags(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	for _, id := range []string{"1", "2", "3"} {
+		entry := wallet.SearchEntryByID(id)
+		entry.Tags = []string{"work"}
+		if id == "1" {
+			entry.Tags = append(entry.Tags, "Bank")
+		}
+		wallet.UpdateEntry(entry)
+	}
+
+	tags := wallet.Tags()
+	if len(tags) != 2 {
+		t.Fatalf("must have 2 tags: %d", len(tags))
+	}
+	if tags[0].Name != "Bank" || tags[0].Count != 1 || tags[1].Name != "work" || tags[1].Count != 3 {
+		t.Errorf("the tags must be sorted with the number of entries: %v", tags)
+	}
+
+	entries := len(wallet.SearchEntry("", "", false, "work"))
+	if entries != 3 {
+		t.Errorf("a search with a tag must return 3 entries: %d", entries)
+	}
+
+	entries = len(wallet.SearchEntry("", "", false, "WORK", "bank"))
+	if entries != 1 {
+		t.Errorf("a search with two tags must return 1 entry: %d", entries)
+	}
+
+	entries = len(wallet.SearchEntry("", "bad group", false, "work"))
+	if entries != 0 {
+		t.Errorf("a search with a tag and a bad group must return 0 entry: %d", entries)
+	}
+}
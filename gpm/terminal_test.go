@@ -0,0 +1,32 @@
+package gpm
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadPassphraseFromFile(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	tmpFile.WriteString("secret\r\nnext line\n")
+	tmpFile.Seek(0, 0)
+
+	passphrase, err := ReadPassphrase("Passphrase: ", tmpFile)
+	if err != nil {
+		t.Errorf("read a passphrase mustn't return an error: %s", err)
+	}
+	if passphrase != "secret" {
+		t.Errorf("the passphrase must be the first line: %s", passphrase)
+	}
+
+	line, _ := readLine(tmpFile)
+	if line != "next line" {
+		t.Errorf("the next lines mustn't be consumed: %s", line)
+	}
+
+	_, err = readLine(tmpFile)
+	if err == nil {
+		t.Error("read an empty input must return an error")
+	}
+}
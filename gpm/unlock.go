@@ -0,0 +1,123 @@
+// Copyright 2019 Adrien Waksberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Passphrase sources, fd and env are followed by the descriptor number or
+// the variable name like fd:3 or env:GPM_PASSPHRASE
+const (
+	SourcePrompt  = "prompt"
+	SourceStdin   = "stdin"
+	SourceFD      = "fd"
+	SourceEnv     = "env"
+	SourceCommand = "command"
+)
+
+// ReadPassphraseSource read the passphrase from stdin, a file descriptor, an
+// environment variable or the output of the command, the returned warnings
+// are about the insecure sources, a file descriptor other than 0 is closed
+// after the read
+func ReadPassphraseSource(source string, command string) (string, []string, error) {
+	var passphrase string
+	var warnings []string
+	var err error
+
+	kind, name := source, ""
+	if index := strings.Index(source, ":"); index >= 0 {
+		kind, name = source[:index], source[index+1:]
+	}
+
+	switch {
+	case kind == SourceStdin && name == "":
+		warnings = fileWarnings(os.Stdin)
+		passphrase, err = ReadPassphrase("Passphrase to unlock the wallet: ", os.Stdin)
+	case kind == SourceFD:
+		fd, convErr := strconv.Atoi(name)
+		if convErr != nil || fd < 0 {
+			return "", nil, fmt.Errorf("the file descriptor %s isn't valid", name)
+		}
+		file := os.Stdin
+		if fd != 0 {
+			file = os.NewFile(uintptr(fd), source)
+			defer file.Close()
+		}
+		warnings = fileWarnings(file)
+		passphrase, err = readLine(file)
+	case kind == SourceEnv:
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			return "", nil, fmt.Errorf("the environment variable %s isn't defined", name)
+		}
+		os.Unsetenv(name)
+		warnings = append(warnings, fmt.Sprintf("the environment variable %s can be read by the other processes of the user, prefer a file descriptor or a command", name))
+		passphrase = value
+	case kind == SourceCommand && name == "":
+		passphrase, err = runPassphraseCommand(command)
+	default:
+		return "", nil, fmt.Errorf("unknown passphrase source %s, it must be prompt, stdin, fd:N, env:NAME or command", source)
+	}
+
+	if err != nil {
+		return "", warnings, fmt.Errorf("failed to read the passphrase from %s: %s", source, err)
+	}
+	if passphrase == "" {
+		return "", warnings, fmt.Errorf("the passphrase from %s is empty", source)
+	}
+
+	return passphrase, warnings, nil
+}
+
+// fileWarnings return a warning if the passphrase is read from a file
+// readable by the other users
+func fileWarnings(file *os.File) []string {
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0044 == 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("the passphrase file is readable by the other users (mode %04o), use the mode 0600", info.Mode().Perm())}
+}
+
+// runPassphraseCommand return the first line printed by the command, the
+// arguments are separated by spaces and aren't interpreted by a shell
+func runPassphraseCommand(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("you must define passphrase_command in the config")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		cmd.Stdin = os.Stdin
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.SplitN(string(output), "\n", 2)[0]
+	return strings.TrimSuffix(line, "\r"), nil
+}
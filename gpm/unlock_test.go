@@ -0,0 +1,114 @@
+package gpm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestPassphraseFromEnv(t *testing.T) {
+	os.Setenv("GPM_TEST_PASSPHRASE", "secret")
+
+	passphrase, warnings, err := ReadPassphraseSource("env:GPM_TEST_PASSPHRASE", "")
+	if err != nil {
+		t.Fatalf("read the passphrase from the environment mustn't return an error: %s", err)
+	}
+	if passphrase != "secret" {
+		t.Errorf("the passphrase must be the variable value: %s", passphrase)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("the environment must return a warning: %v", warnings)
+	}
+	if _, ok := os.LookupEnv("GPM_TEST_PASSPHRASE"); ok {
+		t.Error("the variable must be removed from the environment")
+	}
+
+	_, _, err = ReadPassphraseSource("env:GPM_TEST_PASSPHRASE", "")
+	if err == nil {
+		t.Error("an undefined variable must return an error")
+	}
+}
+
+func TestPassphraseFromFD(t *testing.T) {
+	reader, writer, _ := os.Pipe()
+	writer.WriteString("secret\n")
+	writer.Close()
+
+	passphrase, warnings, err := ReadPassphraseSource(fmt.Sprintf("fd:%d", reader.Fd()), "")
+	if err != nil {
+		t.Fatalf("read the passphrase from a file descriptor mustn't return an error: %s", err)
+	}
+	if passphrase != "secret" || len(warnings) != 0 {
+		t.Errorf("the passphrase must be read without warning: %s %v", passphrase, warnings)
+	}
+
+	_, _, err = ReadPassphraseSource("fd:abc", "")
+	if err == nil {
+		t.Error("a bad file descriptor must return an error")
+	}
+}
+
+func TestPassphraseFromReadableFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the file modes aren't supported")
+	}
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	tmpFile.WriteString("secret\n")
+	tmpFile.Close()
+
+	os.Chmod(tmpFile.Name(), 0644)
+	file, _ := os.Open(tmpFile.Name())
+	_, warnings, err := ReadPassphraseSource(fmt.Sprintf("fd:%d", file.Fd()), "")
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("a file readable by the other users must return a warning: %v %v", err, warnings)
+	}
+
+	os.Chmod(tmpFile.Name(), 0600)
+	file, _ = os.Open(tmpFile.Name())
+	_, warnings, err = ReadPassphraseSource(fmt.Sprintf("fd:%d", file.Fd()), "")
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("a private file mustn't return a warning: %v %v", err, warnings)
+	}
+}
+
+func TestPassphraseFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("echo isn't a command")
+	}
+
+	passphrase, _, err := ReadPassphraseSource(SourceCommand, "echo secret passphrase")
+	if err != nil {
+		t.Fatalf("read the passphrase from a command mustn't return an error: %s", err)
+	}
+	if passphrase != "secret passphrase" {
+		t.Errorf("the passphrase must be the command output: %s", passphrase)
+	}
+
+	_, _, err = ReadPassphraseSource(SourceCommand, "false")
+	if err == nil {
+		t.Error("a failed command must return an error")
+	}
+
+	_, _, err = ReadPassphraseSource(SourceCommand, "true")
+	if err == nil {
+		t.Error("an empty passphrase must return an error")
+	}
+
+	_, _, err = ReadPassphraseSource(SourceCommand, "")
+	if err == nil {
+		t.Error("a source command without command must return an error")
+	}
+}
+
+func TestUnknownPassphraseSource(t *testing.T) {
+	for _, source := range []string{"file", "stdin:1", "command:echo"} {
+		_, _, err := ReadPassphraseSource(source, "echo secret")
+		if err == nil {
+			t.Errorf("the source %s must return an error", source)
+		}
+	}
+}
@@ -15,35 +15,119 @@
 package gpm
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 )
 
+// Wallet file format
+const (
+	WalletVersion   = 3
+	CipherAES256GCM = "aes-256-gcm"
+)
+
+// WalletHeader contains the wallet metadata, it's authenticated with the data
+type WalletHeader struct {
+	Version int
+	Cipher  string
+	KDF     KDF
+	Salt    string
+	Create  int64
+}
+
 // WalletFile contains the data in file
 type WalletFile struct {
-	Salt string
+	WalletHeader
 	Data string
 }
 
+// WalletData contains the encrypted data, the files before the version 3
+// have only the entries
+type WalletData struct {
+	Entries []Entry
+	Trash   []Entry `json:",omitempty"`
+}
+
 // Wallet struct have wallet informations
 type Wallet struct {
 	Name       string
 	Path       string
 	Salt       string
+	KDF        KDF
+	Create     int64
 	Passphrase string
+	MaxBackups int
+	MaxHistory int
+	ReadOnly   bool
 	Entries    []Entry
+	Trash      []Entry
+	Retention  int
+	Resolve    MergeResolver
+	Git        *GitRepository
+
+	lockFile *os.File
+	hash     [sha256.Size]byte
+	base     WalletData
+}
+
+// AdditionalData return the header to authenticate with the encrypted data,
+// the files before the version 2 haven't authenticated header
+func (h *WalletHeader) AdditionalData() ([]byte, error) {
+	if h.Version < 2 {
+		return nil, nil
+	}
+
+	return json.Marshal(h)
+}
+
+// Migrate the header from an old file format to the current version
+func (h *WalletHeader) Migrate() error {
+	if h.Version > WalletVersion {
+		return fmt.Errorf("the wallet format version %d isn't supported, upgrade gpm", h.Version)
+	}
+
+	if h.Version < 2 {
+		h.Cipher = CipherAES256GCM
+		if h.KDF.Name == "" {
+			h.KDF = LegacyKDF()
+		}
+	}
+
+	if h.Cipher != CipherAES256GCM {
+		return fmt.Errorf("the wallet cipher %s isn't supported", h.Cipher)
+	}
+
+	h.Version = WalletVersion
+
+	return nil
 }
 
 // Load all wallet's Entrys from the disk
 func (w *Wallet) Load() error {
-	var walletFile WalletFile
+	err := w.Lock()
+	if err != nil {
+		return err
+	}
 
+	err = w.read()
+	if err != nil {
+		w.Unlock()
+	}
+
+	return err
+}
+
+// read the wallet file
+func (w *Wallet) read() error {
 	_, err := os.Stat(w.Path)
 	if err != nil {
 		return nil
@@ -54,52 +138,228 @@ func (w *Wallet) Load() error {
 		return err
 	}
 
-	err = json.Unmarshal(content, &walletFile)
+	header, walletData, err := w.decode(content)
 	if err != nil {
 		return err
 	}
 
-	w.Salt = walletFile.Salt
-	data, err := Decrypt(string(walletFile.Data), w.Passphrase, w.Salt)
+	w.Salt = header.Salt
+	w.KDF = header.KDF
+	w.Create = header.Create
+	w.Entries = walletData.Entries
+	w.Trash = walletData.Trash
+	w.snapshot(content)
+
+	return nil
+}
+
+// decode decrypt the content of a wallet file
+func (w *Wallet) decode(content []byte) (WalletHeader, WalletData, error) {
+	var walletFile WalletFile
+	var walletData WalletData
+
+	err := json.Unmarshal(content, &walletFile)
 	if err != nil {
-		return err
+		return WalletHeader{}, walletData, err
 	}
 
-	err = json.Unmarshal(data, &w.Entries)
+	header := walletFile.WalletHeader
+	version := header.Version
+	additionalData, err := header.AdditionalData()
 	if err != nil {
-		return err
+		return WalletHeader{}, walletData, err
+	}
+
+	err = header.Migrate()
+	if err != nil {
+		return WalletHeader{}, walletData, err
+	}
+
+	data, err := Decrypt(walletFile.Data, w.Passphrase, header.Salt, header.KDF, additionalData)
+	if err != nil {
+		return WalletHeader{}, walletData, err
 	}
 
+	if version < 3 {
+		err = json.Unmarshal(data, &walletData.Entries)
+	} else {
+		err = json.Unmarshal(data, &walletData)
+	}
+	if err != nil {
+		return WalletHeader{}, walletData, err
+	}
+
+	return header, walletData, nil
+}
+
+// snapshot keep the file hash and the entries as the base for the next merge
+func (w *Wallet) snapshot(content []byte) {
+	w.hash = sha256.Sum256(content)
+	w.base = WalletData{
+		Entries: append([]Entry{}, w.Entries...),
+		Trash:   append([]Entry{}, w.Trash...),
+	}
+}
+
+// merge the entries with the file on the disk if it has been modified by
+// another program since the last load or save
+func (w *Wallet) merge() error {
+	content, err := ioutil.ReadFile(w.Path)
+	if err != nil || len(content) == 0 || sha256.Sum256(content) == w.hash {
+		return nil
+	}
+
+	_, walletData, err := w.decode(content)
+	if err != nil {
+		return fmt.Errorf("the wallet has been modified by another program and can't be merged: %s", err)
+	}
+
+	result := mergeWalletData(w.base, WalletData{Entries: w.Entries, Trash: w.Trash}, walletData, w.Resolve)
+	w.Entries = result.Entries
+	w.Trash = result.Trash
+	w.hash = sha256.Sum256(content)
+	w.base = walletData
+
 	return nil
 }
 
 // Save the wallet on the disk
 func (w *Wallet) Save() error {
-	if w.Salt == "" {
-		w.Salt = RandomString(12, true, true, false)
+	if w.ReadOnly {
+		return fmt.Errorf("the wallet is open in read-only mode")
 	}
 
-	data, err := json.Marshal(&w.Entries)
+	err := w.Lock()
+	if err != nil {
+		return err
+	}
+
+	err = w.merge()
+	if err != nil {
+		return err
+	}
+
+	w.purgeTrash()
+	before := w.base.Entries
+	err = w.write()
+	if err != nil {
+		return err
+	}
+
+	if w.Git != nil {
+		err = w.Git.Commit(filepath.Base(w.Path), commitMessage(w.Name, before, w.Entries))
+		if err != nil {
+			return fmt.Errorf("the wallet has been saved, but the git commit failed: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// write encrypt the entries and replace the wallet file
+func (w *Wallet) write() error {
+	if w.Salt == "" || w.KDF != DefaultKDF() {
+		salt, err := RandomSalt()
+		if err != nil {
+			return err
+		}
+		w.Salt = salt
+		w.KDF = DefaultKDF()
+	}
+
+	if w.Create == 0 {
+		w.Create = time.Now().Unix()
+	}
+
+	data, err := json.Marshal(&WalletData{Entries: w.Entries, Trash: w.Trash})
+	if err != nil {
+		return err
+	}
+
+	header := WalletHeader{
+		Version: WalletVersion,
+		Cipher:  CipherAES256GCM,
+		KDF:     w.KDF,
+		Salt:    w.Salt,
+		Create:  w.Create,
+	}
+	additionalData, err := header.AdditionalData()
 	if err != nil {
 		return err
 	}
 
-	dataEncrypted, err := Encrypt(data, w.Passphrase, w.Salt)
+	dataEncrypted, err := Encrypt(data, w.Passphrase, w.Salt, w.KDF, additionalData)
 	if err != nil {
 		return err
 	}
 
-	walletFile := WalletFile{Salt: w.Salt, Data: dataEncrypted}
+	walletFile := WalletFile{WalletHeader: header, Data: dataEncrypted}
 	content, err := json.Marshal(&walletFile)
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(w.Path, content, 0600)
+	err = w.backup()
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(w.Path, content)
+	if err != nil {
+		return err
+	}
+	w.snapshot(content)
+
+	return nil
+}
+
+// ChangePassphrase encrypt the wallet with a new passphrase and a new salt,
+// the backups encrypted with the old passphrase are removed
+func (w *Wallet) ChangePassphrase(oldPassphrase string, newPassphrase string) error {
+	if w.ReadOnly {
+		return fmt.Errorf("the wallet is open in read-only mode")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(oldPassphrase), []byte(w.Passphrase)) != 1 {
+		return fmt.Errorf("the current passphrase is wrong")
+	}
+
+	if newPassphrase == "" {
+		return fmt.Errorf("the new passphrase can't be empty")
+	}
+
+	err := w.Lock()
+	if err != nil {
+		return err
+	}
+
+	err = w.merge()
 	if err != nil {
 		return err
 	}
 
+	newSalt, err := RandomSalt()
+	if err != nil {
+		return err
+	}
+
+	salt, kdf := w.Salt, w.KDF
+	w.Salt = newSalt
+	w.KDF = DefaultKDF()
+	w.Passphrase = newPassphrase
+
+	err = w.Save()
+	if err != nil {
+		w.Salt, w.KDF, w.Passphrase = salt, kdf, oldPassphrase
+		return err
+	}
+
+	// the backups are encrypted with the old passphrase
+	err = w.removeBackups()
+	if err != nil {
+		return fmt.Errorf("the passphrase has been changed, but the old backups can't be removed: %s", err)
+	}
+
 	return nil
 }
 
@@ -125,17 +385,22 @@ func (w *Wallet) Groups() []string {
 	return groups
 }
 
-// SearchEntry return an array with the array expected with the pattern
-func (w *Wallet) SearchEntry(pattern string, group string, noGroup bool) []Entry {
+// SearchEntry return an array with the array expected with the pattern,
+// the entries must be in the group or its descendants and have all the tags
+func (w *Wallet) SearchEntry(pattern string, group string, noGroup bool, tags ...string) []Entry {
 	var entries []Entry
 	r := regexp.MustCompile(strings.ToLower(pattern))
 
 	for _, entry := range w.Entries {
-		if (noGroup && entry.Group != "") || (!noGroup && group != "" && strings.ToLower(entry.Group) != strings.ToLower(group)) {
+		if (noGroup && entry.Group != "") || (!noGroup && group != "" && !InGroup(entry.Group, group)) {
+			continue
+		}
+		if !entry.HasTags(tags...) {
 			continue
 		}
 		if r.Match([]byte(strings.ToLower(entry.Name))) ||
-			r.Match([]byte(strings.ToLower(entry.Comment))) || r.Match([]byte(strings.ToLower(entry.URI))) {
+			r.Match([]byte(strings.ToLower(entry.Comment))) || r.Match([]byte(strings.ToLower(entry.URI))) ||
+			matchFields(r, entry.Fields) {
 			entries = append(entries, entry)
 		}
 	}
@@ -147,6 +412,43 @@ func (w *Wallet) SearchEntry(pattern string, group string, noGroup bool) []Entry
 	return entries
 }
 
+// matchFields return true if a custom field, except the secrets, match the pattern
+func matchFields(r *regexp.Regexp, fields []CustomField) bool {
+	for _, field := range fields {
+		if field.Secret() {
+			continue
+		}
+		if r.MatchString(strings.ToLower(field.Name)) || r.MatchString(strings.ToLower(field.Value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindEntry return the entry with this ID or this name
+func (w *Wallet) FindEntry(nameOrID string) (Entry, error) {
+	var entries []Entry
+
+	for _, entry := range w.Entries {
+		if entry.ID == nameOrID {
+			return entry, nil
+		}
+		if strings.EqualFold(entry.Name, nameOrID) {
+			entries = append(entries, entry)
+		}
+	}
+
+	switch len(entries) {
+	case 0:
+		return Entry{}, fmt.Errorf("the entry %s doesn't exist", nameOrID)
+	case 1:
+		return entries[0], nil
+	}
+
+	return Entry{}, fmt.Errorf("%d entries are named %s, use the ID", len(entries), nameOrID)
+}
+
 // SearchEntryByID return an Entry
 func (w *Wallet) SearchEntryByID(id string) Entry {
 	for _, entry := range w.Entries {
@@ -165,22 +467,26 @@ func (w *Wallet) AddEntry(entry Entry) error {
 		return err
 	}
 
-	if w.SearchEntryByID(entry.ID) != (Entry{}) {
+	if w.SearchEntryByID(entry.ID).ID != "" {
 		return fmt.Errorf("the id already exists in wallet, can't add the entry")
 	}
 
+	entry.Group = CleanGroup(entry.Group)
 	entry.Create = time.Now().Unix()
 	entry.LastUpdate = entry.Create
+	entry.PasswordChange = entry.Create
 	w.Entries = append(w.Entries, entry)
 
 	return nil
 }
 
-// DeleteEntry delete an entry to wallet
+// DeleteEntry move an entry to the trash
 func (w *Wallet) DeleteEntry(id string) error {
 	for index, entry := range w.Entries {
 		if entry.ID == id {
 			w.Entries = append(w.Entries[:index], w.Entries[index+1:]...)
+			entry.Deleted = time.Now().Unix()
+			w.Trash = append(w.Trash, entry)
 			return nil
 		}
 	}
@@ -188,10 +494,61 @@ func (w *Wallet) DeleteEntry(id string) error {
 	return fmt.Errorf("entry not found with this id")
 }
 
+// RestoreTrashEntry move an entry from the trash to the wallet
+func (w *Wallet) RestoreTrashEntry(id string) error {
+	if w.SearchEntryByID(id).ID != "" {
+		return fmt.Errorf("the id already exists in wallet, can't restore the entry")
+	}
+
+	for index, entry := range w.Trash {
+		if entry.ID == id {
+			w.Trash = append(w.Trash[:index], w.Trash[index+1:]...)
+			entry.Deleted = 0
+			w.Entries = append(w.Entries, entry)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entry not found in the trash with this id")
+}
+
+// PurgeEntry delete definitively an entry from the trash
+func (w *Wallet) PurgeEntry(id string) error {
+	for index, entry := range w.Trash {
+		if entry.ID == id {
+			w.Trash = append(w.Trash[:index], w.Trash[index+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entry not found in the trash with this id")
+}
+
+// EmptyTrash delete definitively all the entries in the trash
+func (w *Wallet) EmptyTrash() {
+	w.Trash = nil
+}
+
+// purgeTrash delete the entries in the trash for more days than the retention
+func (w *Wallet) purgeTrash() {
+	if w.Retention <= 0 {
+		return
+	}
+
+	var trash []Entry
+	limit := time.Now().AddDate(0, 0, -w.Retention).Unix()
+	for _, entry := range w.Trash {
+		if entry.Deleted >= limit {
+			trash = append(trash, entry)
+		}
+	}
+	w.Trash = trash
+}
+
 // UpdateEntry update an Entry to wallet
 func (w *Wallet) UpdateEntry(entry Entry) error {
 	oldEntry := w.SearchEntryByID(entry.ID)
-	if oldEntry == (Entry{}) {
+	if oldEntry.ID == "" {
 		return fmt.Errorf("entry not found with this id")
 	}
 
@@ -200,7 +557,25 @@ func (w *Wallet) UpdateEntry(entry Entry) error {
 		return err
 	}
 
+	entry.Group = CleanGroup(entry.Group)
+	entry.History = oldEntry.History
+	if w.MaxHistory > 0 && !sameVersion(entry, oldEntry) {
+		oldEntry.History = nil
+		oldEntry.Attachments = nil
+		entry.History = append([]Entry{oldEntry}, entry.History...)
+	}
+	if w.MaxHistory > 0 && len(entry.History) > w.MaxHistory {
+		entry.History = entry.History[:w.MaxHistory]
+	}
+	if len(entry.History) == 0 {
+		entry.History = nil
+	}
+
 	entry.LastUpdate = time.Now().Unix()
+	entry.PasswordChange = oldEntry.PasswordChange
+	if entry.Password != oldEntry.Password {
+		entry.PasswordChange = entry.LastUpdate
+	}
 	for index, i := range w.Entries {
 		if entry.ID == i.ID {
 			w.Entries[index] = entry
@@ -211,6 +586,36 @@ func (w *Wallet) UpdateEntry(entry Entry) error {
 	return fmt.Errorf("unknown error during the update")
 }
 
+// RestoreEntry restore an old version of an entry from its history, the
+// current version is kept in the history
+func (w *Wallet) RestoreEntry(id string, version int) error {
+	entry := w.SearchEntryByID(id)
+	if entry.ID == "" {
+		return fmt.Errorf("entry not found with this id")
+	}
+
+	if version < 0 || version >= len(entry.History) {
+		return fmt.Errorf("the version %d doesn't exist in the entry history", version)
+	}
+
+	oldEntry := entry.History[version]
+	oldEntry.History = entry.History
+	oldEntry.Attachments = entry.Attachments
+
+	return w.UpdateEntry(oldEntry)
+}
+
+// sameVersion return true if the entries have the same data, without the
+// update dates, the history and the attachments
+func sameVersion(a Entry, b Entry) bool {
+	a.LastUpdate, b.LastUpdate = 0, 0
+	a.PasswordChange, b.PasswordChange = 0, 0
+	a.History, b.History = nil, nil
+	a.Attachments, b.Attachments = nil, nil
+
+	return reflect.DeepEqual(a, b)
+}
+
 // Import a wallet from a json string
 func (w *Wallet) Import(data []byte) error {
 	var entries []Entry
@@ -239,3 +644,42 @@ func (w *Wallet) Export() ([]byte, error) {
 
 	return data, nil
 }
+
+// writeFileAtomic write the data in a temporary file and rename it to replace the file
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s-", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpFile.Name(), 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpFile.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	// the directory sync isn't supported on all systems
+	dir, err := os.Open(filepath.Dir(path))
+	if err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
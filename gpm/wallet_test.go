@@ -1,10 +1,12 @@
 package gpm
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func generateWalletWithEntries() Wallet {
@@ -60,6 +62,31 @@ func TestSearchEntryWithGoodID(t *testing.T) {
 	}
 }
 
+func TestFindEntry(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.AddEntry(Entry{ID: "10", Name: "Entry 1"})
+
+	entry, err := wallet.FindEntry("5")
+	if err != nil || entry.ID != "5" {
+		t.Errorf("find an entry by ID mustn't return an error: %s", err)
+	}
+
+	entry, err = wallet.FindEntry("entry 2")
+	if err != nil || entry.ID != "2" {
+		t.Errorf("find an entry by name mustn't return an error: %s", err)
+	}
+
+	_, err = wallet.FindEntry("Entry 1")
+	if err == nil {
+		t.Error("find an entry with an ambiguous name must return an error")
+	}
+
+	_, err = wallet.FindEntry("BAD-NAME")
+	if err == nil {
+		t.Error("find an unknown entry must return an error")
+	}
+}
+
 func TestSearchEntriesByGroup(t *testing.T) {
 	wallet := generateWalletWithEntries()
 	entries := len(wallet.SearchEntry("", "BAD-GROUP", false))
@@ -91,6 +118,26 @@ func TestSearchEntriesByPattern(t *testing.T) {
 	}
 }
 
+func TestSearchEntriesByCustomField(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	entry := wallet.SearchEntryByID("5")
+	entry.Fields = []CustomField{
+		{Name: "Account", Type: FieldText, Value: "ACME-42"},
+		{Name: "API key", Type: FieldSecret, Value: "hidden-token"},
+	}
+	wallet.UpdateEntry(entry)
+
+	entries := len(wallet.SearchEntry("acme-42", "", false))
+	if entries != 1 {
+		t.Errorf("a search with a custom field value must return 1 entry: %d", entries)
+	}
+
+	entries = len(wallet.SearchEntry("hidden-token", "", false))
+	if entries != 0 {
+		t.Errorf("a search mustn't match a secret field: %d", entries)
+	}
+}
+
 func TestSearchEntriesByPatternAndGroup(t *testing.T) {
 	wallet := generateWalletWithEntries()
 	entries := len(wallet.SearchEntry("entry", "good group", false))
@@ -125,6 +172,65 @@ func TestDeleteEntry(t *testing.T) {
 	if wallet.SearchEntryByID("5").ID != "" {
 		t.Error("must return an empty entry for the ID 5")
 	}
+
+	if len(wallet.Trash) != 1 || wallet.Trash[0].Deleted == 0 {
+		t.Errorf("the entry must be moved in the trash with the deletion date: %d", len(wallet.Trash))
+	}
+}
+
+func TestRestoreAndPurgeTrash(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.DeleteEntry("5")
+	wallet.DeleteEntry("6")
+
+	err := wallet.RestoreTrashEntry("5")
+	if err != nil {
+		t.Errorf("restore an entry from the trash mustn't return an error: %s", err)
+	}
+	if wallet.SearchEntryByID("5").ID != "5" || wallet.SearchEntryByID("5").Deleted != 0 {
+		t.Error("the entry must be restored in the wallet")
+	}
+
+	err = wallet.PurgeEntry("5")
+	if err == nil {
+		t.Error("purge an entry not in the trash must return an error")
+	}
+
+	err = wallet.PurgeEntry("6")
+	if err != nil {
+		t.Errorf("purge an entry mustn't return an error: %s", err)
+	}
+	if len(wallet.Trash) != 0 {
+		t.Errorf("the trash must be empty: %d", len(wallet.Trash))
+	}
+}
+
+func TestSaveWalletWithTrash(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = tmpFile.Name()
+	wallet.Passphrase = "secret"
+	wallet.Retention = 30
+	wallet.DeleteEntry("5")
+	wallet.DeleteEntry("6")
+	wallet.Trash[1].Deleted = time.Now().AddDate(0, 0, -31).Unix()
+	wallet.Save()
+	wallet.Unlock()
+
+	loadWallet := Wallet{Path: wallet.Path, Passphrase: "secret"}
+	err := loadWallet.Load()
+	if err != nil {
+		t.Errorf("load wallet mustn't return an error: %s", err)
+	}
+	if len(loadWallet.Entries) != 8 {
+		t.Errorf("must have 8 entries: %d", len(loadWallet.Entries))
+	}
+	if len(loadWallet.Trash) != 1 || loadWallet.Trash[0].ID != "5" {
+		t.Errorf("the trash must have only the entry deleted after the retention: %d", len(loadWallet.Trash))
+	}
 }
 
 func TestUpdateNotExistingEntry(t *testing.T) {
@@ -153,6 +259,71 @@ func TestUpdateEntry(t *testing.T) {
 	}
 }
 
+func TestUpdateEntryHistory(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.MaxHistory = 2
+
+	for i := 0; i < 3; i++ {
+		wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: fmt.Sprintf("password %d", i)})
+	}
+
+	entry := wallet.SearchEntryByID("5")
+	if len(entry.History) != 2 {
+		t.Fatalf("the history must have 2 versions: %d", len(entry.History))
+	}
+	if entry.History[0].Password != "password 1" || entry.History[1].Password != "password 0" {
+		t.Errorf("the history must keep the last versions: %s %s", entry.History[0].Password, entry.History[1].Password)
+	}
+
+	wallet.UpdateEntry(entry)
+	if len(wallet.SearchEntryByID("5").History) != 2 {
+		t.Error("an update without change mustn't add a version")
+	}
+}
+
+func TestUpdateEntryWithoutHistory(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.MaxHistory = 2
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: "password 0"})
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: "password 1"})
+
+	wallet.MaxHistory = 0
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: "password 2"})
+
+	entry := wallet.SearchEntryByID("5")
+	if len(entry.History) != 2 {
+		t.Fatalf("the history mustn't be removed without history: %d", len(entry.History))
+	}
+	if entry.History[0].Password != "password 0" {
+		t.Errorf("a version mustn't be added without history: %s", entry.History[0].Password)
+	}
+}
+
+func TestRestoreEntry(t *testing.T) {
+	wallet := generateWalletWithEntries()
+	wallet.MaxHistory = 10
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: "old password"})
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5", Password: "new password"})
+
+	err := wallet.RestoreEntry("5", 5)
+	if err == nil {
+		t.Error("restore a version which doesn't exist must return an error")
+	}
+
+	err = wallet.RestoreEntry("5", 0)
+	if err != nil {
+		t.Errorf("restore a version mustn't return an error: %s", err)
+	}
+
+	entry := wallet.SearchEntryByID("5")
+	if entry.Password != "old password" {
+		t.Errorf("the old password must be restored: %s", entry.Password)
+	}
+	if entry.History[0].Password != "new password" {
+		t.Errorf("the current version must be kept in the history: %s", entry.History[0].Password)
+	}
+}
+
 func TestExportAndImport(t *testing.T) {
 	wallet := generateWalletWithEntries()
 	export, err := wallet.Export()
@@ -175,6 +346,7 @@ func TestExportAndImport(t *testing.T) {
 func TestSaveWallet(t *testing.T) {
 	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
 
 	wallet := generateWalletWithEntries()
 	wallet.Path = tmpFile.Name()
@@ -191,11 +363,13 @@ func TestLoadWalletWithGoodPassword(t *testing.T) {
 
 	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
 
 	wallet := generateWalletWithEntries()
 	wallet.Path = tmpFile.Name()
 	wallet.Passphrase = "secret"
 	wallet.Save()
+	wallet.Unlock()
 	loadWallet.Path = wallet.Path
 	loadWallet.Passphrase = wallet.Passphrase
 
@@ -215,6 +389,7 @@ func TestLoadWalletWithBadPassword(t *testing.T) {
 
 	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
 
 	wallet := generateWalletWithEntries()
 	wallet.Path = tmpFile.Name()
@@ -234,6 +409,230 @@ func TestLoadWalletWithBadPassword(t *testing.T) {
 	}
 }
 
+func TestLoadLegacyWalletAndUpgrade(t *testing.T) {
+	var loadWallet Wallet
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	data, _ := json.Marshal([]Entry{{ID: "1", Name: "Entry 1"}})
+	dataEncrypted, _ := Encrypt(data, "secret", "salt", LegacyKDF(), nil)
+	content, _ := json.Marshal(map[string]string{"Salt": "salt", "Data": dataEncrypted})
+	ioutil.WriteFile(tmpFile.Name(), content, 0600)
+
+	loadWallet.Path = tmpFile.Name()
+	loadWallet.Passphrase = "secret"
+	err := loadWallet.Load()
+	if err != nil {
+		t.Errorf("load a legacy wallet mustn't return an error: %s", err)
+	}
+	if loadWallet.KDF != LegacyKDF() {
+		t.Errorf("a legacy wallet must use the legacy KDF: %s", loadWallet.KDF.Name)
+	}
+
+	err = loadWallet.Save()
+	if err != nil {
+		t.Errorf("save wallet mustn't return an error: %s", err)
+	}
+	if loadWallet.KDF != DefaultKDF() || loadWallet.Salt == "salt" {
+		t.Errorf("the wallet must be upgraded to the default KDF with a new salt: %s", loadWallet.KDF.Name)
+	}
+
+	loadWallet.Unlock()
+	loadWallet = Wallet{Path: tmpFile.Name(), Passphrase: "secret"}
+	err = loadWallet.Load()
+	if err != nil {
+		t.Errorf("load an upgraded wallet mustn't return an error: %s", err)
+	}
+	if len(loadWallet.Entries) != 1 {
+		t.Errorf("must have 1 entry: %d", len(loadWallet.Entries))
+	}
+}
+
+func TestLoadWalletVersion2(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	header := WalletHeader{Version: 2, Cipher: CipherAES256GCM, KDF: LegacyKDF(), Salt: "salt", Create: 1}
+	additionalData, _ := header.AdditionalData()
+	data, _ := json.Marshal([]Entry{{ID: "1", Name: "Entry 1"}})
+	dataEncrypted, _ := Encrypt(data, "secret", "salt", LegacyKDF(), additionalData)
+	content, _ := json.Marshal(WalletFile{WalletHeader: header, Data: dataEncrypted})
+	ioutil.WriteFile(tmpFile.Name(), content, 0600)
+
+	wallet := Wallet{Path: tmpFile.Name(), Passphrase: "secret"}
+	err := wallet.Load()
+	if err != nil {
+		t.Errorf("load a wallet version 2 mustn't return an error: %s", err)
+	}
+	if len(wallet.Entries) != 1 {
+		t.Errorf("must have 1 entry: %d", len(wallet.Entries))
+	}
+}
+
+func TestLoadWalletWithTamperedHeader(t *testing.T) {
+	var walletFile WalletFile
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = tmpFile.Name()
+	wallet.Passphrase = "secret"
+	wallet.Save()
+
+	content, _ := ioutil.ReadFile(wallet.Path)
+	json.Unmarshal(content, &walletFile)
+	if walletFile.Version != WalletVersion || walletFile.Cipher != CipherAES256GCM {
+		t.Errorf("the wallet file must have the version %d: %d", WalletVersion, walletFile.Version)
+	}
+
+	walletFile.Create = walletFile.Create - 3600
+	content, _ = json.Marshal(&walletFile)
+	ioutil.WriteFile(wallet.Path, content, 0600)
+
+	loadWallet := Wallet{Path: wallet.Path, Passphrase: wallet.Passphrase}
+	err := loadWallet.Load()
+	if err == nil {
+		t.Error("load wallet with a tampered header must return an error")
+	}
+}
+
+func TestLoadWalletWithUnsupportedVersion(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	walletFile := WalletFile{WalletHeader: WalletHeader{Version: WalletVersion + 1}}
+	content, _ := json.Marshal(&walletFile)
+	ioutil.WriteFile(tmpFile.Name(), content, 0600)
+
+	wallet := Wallet{Path: tmpFile.Name(), Passphrase: "secret"}
+	err := wallet.Load()
+	if err == nil {
+		t.Error("load wallet with an unsupported version must return an error")
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = tmpFile.Name()
+	wallet.Passphrase = "secret"
+	wallet.Save()
+	salt := wallet.Salt
+
+	err := wallet.ChangePassphrase("bad secret", "new secret")
+	if err == nil {
+		t.Error("change passphrase with a bad passphrase must return an error")
+	}
+
+	err = wallet.ChangePassphrase("secret", "new secret")
+	if err != nil {
+		t.Errorf("change passphrase mustn't return an error: %s", err)
+	}
+	if wallet.Salt == salt {
+		t.Error("change passphrase must generate a new salt")
+	}
+
+	wallet.Unlock()
+	loadWallet := Wallet{Path: wallet.Path, Passphrase: "secret"}
+	err = loadWallet.Load()
+	if err == nil {
+		t.Error("load wallet with the old passphrase must return an error")
+	}
+
+	loadWallet = Wallet{Path: wallet.Path, Passphrase: "new secret"}
+	err = loadWallet.Load()
+	if err != nil {
+		t.Errorf("load wallet with the new passphrase mustn't return an error: %s", err)
+	}
+
+	entries := len(loadWallet.Entries)
+	if entries != 10 {
+		t.Errorf("must have 10 entries: %d", entries)
+	}
+}
+
+func TestSaveWalletModifiedByAnotherProgram(t *testing.T) {
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = tmpFile.Name()
+	wallet.Passphrase = "secret"
+	wallet.Save()
+	wallet.Unlock()
+
+	otherWallet := Wallet{Path: wallet.Path, Passphrase: "secret"}
+	otherWallet.Load()
+	otherWallet.AddEntry(Entry{ID: "10", Name: "Entry 10"})
+	otherWallet.Save()
+	otherWallet.Unlock()
+
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5 updated"})
+	err := wallet.Save()
+	if err != nil {
+		t.Errorf("save a wallet modified by another program mustn't return an error: %s", err)
+	}
+
+	wallet.Unlock()
+	loadWallet := Wallet{Path: wallet.Path, Passphrase: "secret"}
+	loadWallet.Load()
+	if len(loadWallet.Entries) != 11 {
+		t.Errorf("must have 11 entries: %d", len(loadWallet.Entries))
+	}
+	if loadWallet.SearchEntryByID("10").ID != "10" {
+		t.Error("the entry added by another program must be kept")
+	}
+	if loadWallet.SearchEntryByID("5").Name != "Entry 5 updated" {
+		t.Errorf("the updated entry must be kept: %s", loadWallet.SearchEntryByID("5").Name)
+	}
+}
+
+func TestSaveWalletWithConflict(t *testing.T) {
+	var conflicts []MergeConflict
+
+	tmpFile, _ := ioutil.TempFile(os.TempDir(), "gpm_test-")
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
+
+	wallet := generateWalletWithEntries()
+	wallet.Path = tmpFile.Name()
+	wallet.Passphrase = "secret"
+	wallet.Resolve = func(conflict MergeConflict) *Entry {
+		conflicts = append(conflicts, conflict)
+		return conflict.Theirs
+	}
+	wallet.Save()
+	wallet.Unlock()
+
+	otherWallet := Wallet{Path: wallet.Path, Passphrase: "secret"}
+	otherWallet.Load()
+	otherWallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5 theirs"})
+	otherWallet.Save()
+	otherWallet.Unlock()
+
+	wallet.UpdateEntry(Entry{ID: "5", Name: "Entry 5 ours"})
+	wallet.Save()
+	if len(conflicts) != 1 {
+		t.Fatalf("must have 1 conflict: %d", len(conflicts))
+	}
+	if conflicts[0].ID != "5" {
+		t.Errorf("the conflict must be on the entry 5: %s", conflicts[0].ID)
+	}
+	if wallet.SearchEntryByID("5").Name != "Entry 5 theirs" {
+		t.Errorf("the entry chosen by the resolver must be kept: %s", wallet.SearchEntryByID("5").Name)
+	}
+}
+
 func TestGetGroup(t *testing.T) {
 	wallet := generateWalletWithEntries()
 	groups := wallet.Groups()